@@ -0,0 +1,71 @@
+// Command fixtures manages docker resources left behind by go-fixtures.
+//
+//	fixtures list                     List containers kept for reuse across test runs.
+//	fixtures prune [-older-than 24h]  Remove containers kept for reuse.
+//	fixtures reap [-older-than 1h]    Remove containers and networks left behind by crashed test runs.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	fixtures "github.com/charlieparkes/go-fixtures/v2"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	ctx := context.Background()
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "list":
+		err = list(ctx)
+	case "prune":
+		flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+		olderThan := flags.Duration("older-than", 0, "only remove containers created longer ago than this")
+		flags.Parse(args)
+		err = prune(ctx, *olderThan)
+	case "reap":
+		flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+		olderThan := flags.Duration("older-than", time.Hour, "only remove resources created longer ago than this")
+		flags.Parse(args)
+		err = fixtures.Reap(ctx, *olderThan)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fixtures: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: fixtures list | prune [-older-than duration] | reap [-older-than duration]")
+	os.Exit(2)
+}
+
+func list(ctx context.Context) error {
+	reused, err := fixtures.ListReused(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tIMAGE\tSTATE\tCREATED")
+	for _, c := range reused {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v ago\n", c.Name, c.Image, c.State, time.Since(c.Created).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+func prune(ctx context.Context, olderThan time.Duration) error {
+	pruned, err := fixtures.PruneReused(ctx, olderThan)
+	for _, c := range pruned {
+		fmt.Println(c.Name)
+	}
+	return err
+}
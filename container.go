@@ -0,0 +1,431 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"go.uber.org/zap"
+)
+
+type ContainerOpt func(*Container)
+
+// NewContainer returns a fixture which runs an arbitrary image on the given Docker fixture.
+func NewContainer(d *Docker, opts ...ContainerOpt) *Container {
+	f := &Container{
+		docker: d,
+		labels: map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func ContainerRepo(repo string) ContainerOpt {
+	return func(f *Container) {
+		f.repo = repo
+	}
+}
+
+// ContainerImage runs an image built by an Image fixture, which is set up first.
+func ContainerImage(image *Image) ContainerOpt {
+	return func(f *Container) {
+		f.image = image
+	}
+}
+
+// Defaults to "latest".
+func ContainerTag(tag string) ContainerOpt {
+	return func(f *Container) {
+		f.tag = tag
+	}
+}
+
+// Name the container. Defaults to a random name.
+func ContainerName(name string) ContainerOpt {
+	return func(f *Container) {
+		f.name = name
+	}
+}
+
+// Environment variables, formatted as "KEY=value".
+func ContainerEnv(env ...string) ContainerOpt {
+	return func(f *Container) {
+		f.env = append(f.env, env...)
+	}
+}
+
+func ContainerCmd(cmd ...string) ContainerOpt {
+	return func(f *Container) {
+		f.cmd = cmd
+	}
+}
+
+func ContainerEntrypoint(entrypoint ...string) ContainerOpt {
+	return func(f *Container) {
+		f.entrypoint = entrypoint
+	}
+}
+
+// Ports to expose in addition to those exposed by the image, e.g. "6379" or "53/udp".
+func ContainerExposedPorts(ports ...string) ContainerOpt {
+	return func(f *Container) {
+		for _, p := range ports {
+			if !strings.Contains(p, "/") {
+				p += "/tcp"
+			}
+			f.exposedPorts = append(f.exposedPorts, p)
+		}
+	}
+}
+
+// Bind mounts, formatted as "/host/path:/container/path".
+func ContainerMounts(mounts ...string) ContainerOpt {
+	return func(f *Container) {
+		f.mounts = append(f.mounts, mounts...)
+	}
+}
+
+func ContainerLabels(labels map[string]string) ContainerOpt {
+	return func(f *Container) {
+		for k, v := range labels {
+			f.labels[k] = v
+		}
+	}
+}
+
+// ContainerReady is retried after the container starts until it returns nil or the ready timeout elapses.
+func ContainerReady(ready func(context.Context, *Container) error) ContainerOpt {
+	return func(f *Container) {
+		f.wait = append(f.wait, WaitFunc(func(ctx context.Context, _ WaitTarget) error {
+			return ready(ctx, f)
+		}))
+	}
+}
+
+// ContainerWaitFor waits for each strategy to pass after the container starts, until the ready timeout elapses.
+func ContainerWaitFor(strategies ...WaitStrategy) ContainerOpt {
+	return func(f *Container) {
+		f.wait = append(f.wait, strategies...)
+	}
+}
+
+// Wait this long for the container to become ready. Defaults to 30 seconds.
+func ContainerReadyTimeout(timeout time.Duration) ContainerOpt {
+	return func(f *Container) {
+		f.readyTimeout = timeout
+	}
+}
+
+// Tell docker to kill the container after an unreasonable amount of test time to prevent orphans. Defaults to 600 seconds.
+func ContainerExpireAfter(expireAfter uint) ContainerOpt {
+	return func(f *Container) {
+		f.expireAfter = expireAfter
+	}
+}
+
+// ContainerHostConfig modifies the host config for settings not covered by other options.
+func ContainerHostConfig(hcOpt func(*docker.HostConfig)) ContainerOpt {
+	return func(f *Container) {
+		f.hcOpts = append(f.hcOpts, hcOpt)
+	}
+}
+
+// Limit the container's memory, without swap. Docker doesn't limit it by default.
+func ContainerMemoryMB(mb int64) ContainerOpt {
+	return func(f *Container) {
+		f.memoryMB = mb
+		f.hcOpts = append(f.hcOpts, func(hc *docker.HostConfig) {
+			hc.Memory = mb * 1e6
+			hc.MemorySwap = hc.Memory
+		})
+	}
+}
+
+// Limit the container to this many CPUs, e.g. 0.5 or 2.
+func ContainerCPUs(cpus float64) ContainerOpt {
+	return func(f *Container) {
+		f.hcOpts = append(f.hcOpts, func(hc *docker.HostConfig) {
+			hc.CPUPeriod = 100000
+			hc.CPUQuota = int64(cpus * 100000)
+		})
+	}
+}
+
+// Size of /dev/shm. Docker defaults to 64MB.
+func ContainerShmSizeMB(mb int64) ContainerOpt {
+	return func(f *Container) {
+		f.hcOpts = append(f.hcOpts, func(hc *docker.HostConfig) {
+			hc.ShmSize = mb * 1e6
+		})
+	}
+}
+
+// Mount a tmpfs at path inside the container, with mount options such as "rw,size=256m".
+func ContainerTmpfs(path string, options string) ContainerOpt {
+	return func(f *Container) {
+		f.hcOpts = append(f.hcOpts, func(hc *docker.HostConfig) {
+			if hc.Tmpfs == nil {
+				hc.Tmpfs = map[string]string{}
+			}
+			hc.Tmpfs[path] = options
+		})
+	}
+}
+
+// Reuse a running container with identical configuration, left behind by an earlier test run, instead of
+// starting a new one. The container is left running after teardown. See PruneReused.
+func ContainerReuse() ContainerOpt {
+	return func(f *Container) {
+		f.reuse = true
+	}
+}
+
+func ContainerSkipTearDown() ContainerOpt {
+	return func(f *Container) {
+		f.skipTearDown = true
+	}
+}
+
+// Stream the container's stdout and stderr to w, one line per write. See LogWriter to stream to the test log.
+func ContainerLogOutput(w io.Writer) ContainerOpt {
+	return func(f *Container) {
+		f.logOutput = w
+	}
+}
+
+// Prefix each line streamed to the log output. Defaults to "[<container name>] ".
+func ContainerLogPrefix(prefix string) ContainerOpt {
+	return func(f *Container) {
+		f.logPrefix = &prefix
+	}
+}
+
+// Keep the last n lines of output to report when setup or a test fails. Defaults to 100; a negative n keeps none.
+func ContainerLogTail(n int) ContainerOpt {
+	return func(f *Container) {
+		f.logTail = n
+	}
+}
+
+func ContainerLogger(logger *zap.Logger) ContainerOpt {
+	return func(f *Container) {
+		f.log = logger
+	}
+}
+
+type Container struct {
+	BaseFixture
+	log          *zap.Logger
+	docker       *Docker
+	resource     *dockertest.Resource
+	image        *Image
+	repo         string
+	tag          string
+	name         string
+	env          []string
+	cmd          []string
+	entrypoint   []string
+	exposedPorts []string
+	mounts       []string
+	labels       map[string]string
+	hcOpts       []func(*docker.HostConfig)
+	memoryMB     int64
+	wait         []WaitStrategy
+	readyTimeout time.Duration
+	expireAfter  uint
+	skipTearDown bool
+	reuse        bool
+	reused       bool
+	connected    bool
+	logOutput    io.Writer
+	logPrefix    *string
+	logTail      int
+	// logsMu guards logs, which LogTail may read while SetUp is still running after timing out.
+	logsMu   sync.Mutex
+	logs     *logRing
+	stopLogs context.CancelFunc
+}
+
+func (f *Container) Docker() *Docker {
+	return f.docker
+}
+
+func (f *Container) Resource() *dockertest.Resource {
+	return f.resource
+}
+
+// Reused reports whether an existing container was reused rather than started by this fixture.
+func (f *Container) Reused() bool {
+	return f.reused
+}
+
+// MemoryMB returns the container's memory limit, or 0 if it is unlimited. See ContainerMemoryMB.
+func (f *Container) MemoryMB() int64 {
+	return f.memoryMB
+}
+
+// Dependencies reports the Docker fixture this container runs on, and the Image it runs, if any.
+func (f *Container) Dependencies() []Fixture {
+	deps := []Fixture{}
+	if f.docker != nil {
+		deps = append(deps, f.docker)
+	}
+	if f.image != nil {
+		deps = append(deps, f.image)
+	}
+	return deps
+}
+
+func (f *Container) SetUp(ctx context.Context) error {
+	if f.log == nil {
+		f.log = logger()
+	}
+	if f.image != nil {
+		f.repo = f.image.Repo()
+		f.tag = f.image.Tag()
+	}
+	if f.repo == "" {
+		return fmt.Errorf("container image repository is required")
+	}
+	networks := make([]*dockertest.Network, 0)
+	if f.docker.Network() != nil {
+		networks = append(networks, f.docker.Network())
+	}
+	opts := dockertest.RunOptions{
+		Name:         f.name,
+		Repository:   f.repo,
+		Tag:          f.tag,
+		Env:          f.env,
+		Cmd:          f.cmd,
+		Entrypoint:   f.entrypoint,
+		ExposedPorts: f.exposedPorts,
+		Mounts:       f.mounts,
+		Labels:       f.labels,
+		Networks:     networks,
+	}
+	if f.reusable() {
+		if err := f.runReusable(&opts); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if f.resource, err = f.docker.RunWithOptions(&opts, f.hcOpts...); err != nil {
+			return err
+		}
+		if f.expireAfter == 0 {
+			f.expireAfter = 600
+		}
+		f.resource.Expire(f.expireAfter)
+	}
+	f.streamLogs()
+
+	if f.readyTimeout == 0 {
+		f.readyTimeout = 30 * time.Second
+	}
+	return f.WaitForReady(ctx, f.readyTimeout)
+}
+
+func (f *Container) TearDown(context.Context) error {
+	if f.stopLogs != nil {
+		f.stopLogs()
+	}
+	if f.skipTearDown || f.resource == nil {
+		return nil
+	}
+	if f.reusable() {
+		return f.release()
+	}
+	f.docker.Purge(f.resource)
+	return nil
+}
+
+// Exec runs a command inside the container. See Docker.Exec.
+func (f *Container) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	return f.docker.Exec(ctx, f.resource, cmd, opts)
+}
+
+// CopyTo copies a file or directory from the host into dstDir inside the container. See Docker.CopyTo.
+func (f *Container) CopyTo(ctx context.Context, src string, dstDir string) error {
+	return f.docker.CopyTo(ctx, f.resource, src, dstDir)
+}
+
+// CopyToFS copies a file or directory from fsys into dstDir inside the container. See Docker.CopyToFS.
+func (f *Container) CopyToFS(ctx context.Context, fsys fs.FS, src string, dstDir string) error {
+	return f.docker.CopyToFS(ctx, f.resource, fsys, src, dstDir)
+}
+
+// CopyFrom copies a file or directory from inside the container into dstDir on the host. See Docker.CopyFrom.
+func (f *Container) CopyFrom(ctx context.Context, src string, dstDir string) error {
+	return f.docker.CopyFrom(ctx, f.resource, src, dstDir)
+}
+
+// LogTail returns up to the last n lines the container wrote to stdout and stderr, or everything kept if n <= 0.
+func (f *Container) LogTail(n int) []string {
+	f.logsMu.Lock()
+	logs := f.logs
+	f.logsMu.Unlock()
+	if logs == nil {
+		return nil
+	}
+	return logs.tail(n)
+}
+
+func (f *Container) setLogs(logs *logRing) {
+	f.logsMu.Lock()
+	defer f.logsMu.Unlock()
+	f.logs = logs
+}
+
+func (f *Container) streamLogs() {
+	if f.logTail == 0 {
+		f.logTail = DEFAULT_LOG_TAIL
+	}
+	if f.logTail < 0 && f.logOutput == nil {
+		return
+	}
+	logs := newLogRing(f.logTail)
+	f.setLogs(logs)
+	prefix := "[" + f.HostName() + "] "
+	if f.logPrefix != nil {
+		prefix = *f.logPrefix
+	}
+	var ctx context.Context
+	ctx, f.stopLogs = context.WithCancel(context.Background())
+	streamLogs(ctx, f.docker.Pool(), f.resource.Container.ID, logs, f.logOutput, prefix)
+}
+
+// WaitForReady waits for the container's wait strategies to pass, giving up after d.
+func (f *Container) WaitForReady(ctx context.Context, d time.Duration) error {
+	if err := WaitFor(ctx, f, d, f.wait...); err != nil {
+		return fmt.Errorf("container %v is not ready: %w", f.HostName(), err)
+	}
+	return nil
+}
+
+func (f *Container) HostName() string {
+	return HostName(f.resource)
+}
+
+// Host returns the address at which the tests can reach the container. See Docker.ContainerAddress.
+func (f *Container) Host() string {
+	return f.docker.ContainerAddress(f.resource)
+}
+
+// Port returns the port at which the tests can reach the given container port. See ContainerTcpPort.
+func (f *Container) Port(port string) string {
+	return ContainerTcpPort(f.resource, f.docker.Network(), strings.TrimSuffix(port, "/tcp"))
+}
+
+// Address returns the "host:port" at which the tests can reach the given container port.
+func (f *Container) Address(port string) string {
+	return net.JoinHostPort(f.Host(), f.Port(port))
+}
@@ -0,0 +1,65 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	f := New(t)
+	d := NewDocker()
+	c := NewContainer(d,
+		ContainerRepo("crccheck/hello-world"),
+		ContainerExposedPorts("8000"),
+		ContainerLabels(map[string]string{"test": t.Name()}),
+		ContainerReady(func(ctx context.Context, c *Container) error {
+			resp, err := http.Get(fmt.Sprintf("http://%v/", c.Address("8000")))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status: %v", resp.Status)
+			}
+			return nil
+		}),
+	)
+	f.AddT(t, d, c)
+
+	assert.Equal(t, t.Name(), c.Resource().Container.Config.Labels["test"])
+	assert.Equal(t, SessionID(), c.Resource().Container.Config.Labels[LabelSession])
+	assert.NotEmpty(t, c.Port("8000"))
+
+	resp, err := http.Get(fmt.Sprintf("http://%v/", c.Address("8000/tcp")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestContainerResourceOptions(t *testing.T) {
+	c := NewContainer(nil,
+		ContainerMemoryMB(512),
+		ContainerCPUs(1.5),
+		ContainerShmSizeMB(256),
+		ContainerTmpfs("/var/lib/postgresql/data", "rw"),
+	)
+	hc := &docker.HostConfig{}
+	for _, opt := range c.hcOpts {
+		opt(hc)
+	}
+	assert.Equal(t, int64(512), c.MemoryMB())
+	assert.Equal(t, int64(512e6), hc.Memory)
+	assert.Equal(t, hc.Memory, hc.MemorySwap)
+	assert.Equal(t, int64(150000), hc.CPUQuota)
+	assert.Equal(t, int64(100000), hc.CPUPeriod)
+	assert.Equal(t, int64(256e6), hc.ShmSize)
+	assert.Equal(t, map[string]string{"/var/lib/postgresql/data": "rw"}, hc.Tmpfs)
+}
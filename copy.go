@@ -0,0 +1,248 @@
+package fixtures
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// CopyTo copies a file or directory from the host into dstDir inside the container, like `docker cp`. dstDir must
+// already exist. Files are sent over the docker API, so this works when the daemon can't see the host's files.
+func (f *Docker) CopyTo(ctx context.Context, resource *dockertest.Resource, src string, dstDir string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, src))
+	}()
+	err := f.Pool().Client.UploadToContainer(resource.Container.ID, docker.UploadToContainerOptions{
+		Context:     ctx,
+		InputStream: pr,
+		Path:        dstDir,
+	})
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to copy %v to container: %w", src, err)
+	}
+	return nil
+}
+
+// CopyToFS copies a file or directory from fsys into dstDir inside the container. See CopyTo.
+func (f *Docker) CopyToFS(ctx context.Context, resource *dockertest.Resource, fsys fs.FS, src string, dstDir string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarFS(pw, fsys, src))
+	}()
+	err := f.Pool().Client.UploadToContainer(resource.Container.ID, docker.UploadToContainerOptions{
+		Context:     ctx,
+		InputStream: pr,
+		Path:        dstDir,
+	})
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to copy %v to container: %w", src, err)
+	}
+	return nil
+}
+
+// CopyFrom copies a file or directory from inside the container into dstDir on the host, like `docker cp`.
+// dstDir is created if it doesn't exist.
+func (f *Docker) CopyFrom(ctx context.Context, resource *dockertest.Resource, src string, dstDir string) error {
+	pr, pw := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := f.Pool().Client.DownloadFromContainer(resource.Container.ID, docker.DownloadFromContainerOptions{
+			Context:      ctx,
+			OutputStream: pw,
+			Path:         src,
+		})
+		pw.CloseWithError(err)
+		errc <- err
+	}()
+	err := readTar(pr, dstDir)
+	pr.CloseWithError(err)
+	if dlErr := <-errc; dlErr != nil {
+		err = dlErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %v from container: %w", src, err)
+	}
+	return nil
+}
+
+// writeTar archives src, rooted at its base name.
+func writeTar(w io.Writer, src string) error {
+	tw := tar.NewWriter(w)
+	parent := filepath.Dir(filepath.Clean(src))
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// writeTarFS archives src from fsys, rooted at its base name. Symlinks can't be read from an fs.FS, so they're
+// refused.
+func writeTarFS(w io.Writer, fsys fs.FS, src string) error {
+	tw := tar.NewWriter(w)
+	parent := path.Dir(src)
+	err := fs.WalkDir(fsys, src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("can't copy symlink %v", name)
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if parent != "." {
+			hdr.Name = strings.TrimPrefix(name, parent+"/")
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts an archive into dstDir, refusing entries which would be written outside of it.
+func readTar(r io.Reader, dstDir string) error {
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	root := filepath.Clean(dstDir)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(root, filepath.FromSlash(hdr.Name))
+		if !within(root, path) {
+			return fmt.Errorf("archive entry %q is outside of %v", hdr.Name, dstDir)
+		}
+		// An earlier entry may have been a symlink, which must not be written through.
+		if err := noSymlinkParents(root, path); err != nil {
+			return fmt.Errorf("archive entry %q: %w", hdr.Name, err)
+		}
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, mode.Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			target := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(target) || !within(root, filepath.Join(filepath.Dir(path), target)) {
+				return fmt.Errorf("archive entry %q links outside of %v", hdr.Name, dstDir)
+			}
+			os.Remove(path)
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				os.Remove(path)
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// within reports whether path, which must be clean, is root or inside it.
+func within(root string, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// noSymlinkParents returns an error if any directory between root and path is a symlink.
+func noSymlinkParents(root string, path string) error {
+	for dir := filepath.Dir(path); dir != root && within(root, dir); dir = filepath.Dir(dir) {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%v is a symlink", dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package fixtures
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyTarRoundTrip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.sql"), []byte("select 1;"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "nested", "run.sh"), []byte("#!/bin/sh"), 0755))
+	require.NoError(t, os.Symlink("a.sql", filepath.Join(src, "link.sql")))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTar(&buf, src))
+	dst := t.TempDir()
+	require.NoError(t, readTar(&buf, dst))
+
+	b, err := os.ReadFile(filepath.Join(dst, "data", "a.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "select 1;", string(b))
+	info, err := os.Stat(filepath.Join(dst, "data", "nested", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	link, err := os.Readlink(filepath.Join(dst, "data", "link.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.sql", link)
+}
+
+func TestCopyTarOutsideDestination(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escape", Mode: 0644, Typeflag: tar.TypeReg}))
+	require.NoError(t, tw.Close())
+	assert.Error(t, readTar(&buf, t.TempDir()))
+
+	for _, link := range []string{"/etc", "../.."} {
+		outside := t.TempDir()
+		buf.Reset()
+		tw = tar.NewWriter(&buf)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a", Linkname: link, Typeflag: tar.TypeSymlink}))
+		require.NoError(t, tw.Close())
+		assert.Error(t, readTar(&buf, filepath.Join(outside, "dst")), link)
+	}
+
+	// A symlink already in the destination isn't written through.
+	outside := t.TempDir()
+	dst := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(dst, "a")))
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "a/passwd", Mode: 0644, Size: 1, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	assert.Error(t, readTar(&buf, dst))
+	_, err = os.Stat(filepath.Join(outside, "passwd"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestContainerCopy(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	c := NewContainer(d,
+		ContainerRepo("alpine"),
+		ContainerTag("3"),
+		ContainerCmd("sleep", "600"),
+	)
+	f.AddT(t, d, c)
+
+	src := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(src, []byte("hello"), 0644))
+	require.NoError(t, c.CopyTo(ctx, src, "/tmp"))
+
+	res, err := c.Exec(ctx, []string{"cat", "/tmp/hello.txt"}, ExecOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", res.Stdout)
+
+	dst := t.TempDir()
+	require.NoError(t, c.CopyFrom(ctx, "/tmp/hello.txt", dst))
+	b, err := os.ReadFile(filepath.Join(dst, "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestCopyTarFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dumps/app/schema.sql": {Data: []byte("CREATE TABLE t ();"), Mode: 0644},
+		"dumps/app/data.dump":  {Data: []byte("data"), Mode: 0644},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writeTarFS(&buf, fsys, "dumps/app"))
+	dst := t.TempDir()
+	require.NoError(t, readTar(&buf, dst))
+	b, err := os.ReadFile(filepath.Join(dst, "app", "schema.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t ();", string(b))
+
+	buf.Reset()
+	require.NoError(t, writeTarFS(&buf, fsys, "dumps/app/data.dump"))
+	dst = t.TempDir()
+	require.NoError(t, readTar(&buf, dst))
+	b, err = os.ReadFile(filepath.Join(dst, "data.dump"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(b))
+}
@@ -3,9 +3,10 @@ package fixtures
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
+	"net"
 	"os"
+	"sync"
 
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
@@ -40,6 +41,29 @@ func DockerNetworkName(networkName string) DockerOpt {
 	}
 }
 
+// Connect to this daemon endpoint, e.g. "unix:///run/user/1000/podman/podman.sock" or "tcp://10.0.0.2:2375".
+// Defaults to DOCKER_HOST, then the first docker or podman socket found.
+func DockerEndpoint(endpoint string) DockerOpt {
+	return func(f *Docker) {
+		f.endpoint = endpoint
+	}
+}
+
+// Reuse containers across test runs. See ContainerReuse.
+func DockerReuse() DockerOpt {
+	return func(f *Docker) {
+		f.reuse = true
+	}
+}
+
+// Run a sidecar which removes every container and network created through this fixture once it's torn down or
+// the process exits, even if it crashes before tearing down.
+func DockerReaper() DockerOpt {
+	return func(f *Docker) {
+		f.reaper = true
+	}
+}
+
 func DockerLogger(logger *zap.Logger) DockerOpt {
 	return func(f *Docker) {
 		f.log = logger
@@ -53,8 +77,16 @@ type Docker struct {
 	namePrefix     string
 	networkName    string
 	networkExisted bool
+	endpoint       string
+	engine         string
 	pool           *dockertest.Pool
 	network        *dockertest.Network
+	reaper         bool
+	reuse          bool
+	reaperConn     net.Conn
+	purges         sync.WaitGroup
+	mu             sync.Mutex
+	purgeErrs      Errors
 }
 
 func (f *Docker) Name() string {
@@ -68,6 +100,21 @@ func (f *Docker) NetworkName() string {
 	return f.networkName
 }
 
+// Reuse reports whether containers run on this fixture are reused across test runs. See DockerReuse.
+func (f *Docker) Reuse() bool {
+	return f.reuse
+}
+
+// Endpoint returns the daemon endpoint in use, or "" if dockertest's default was used.
+func (f *Docker) Endpoint() string {
+	return f.endpoint
+}
+
+// Engine returns EngineDocker or EnginePodman.
+func (f *Docker) Engine() string {
+	return f.engine
+}
+
 func (f *Docker) Pool() *dockertest.Pool {
 	return f.pool
 }
@@ -100,28 +147,55 @@ func (f *Docker) SetUp(ctx context.Context) error {
 		f.networkName = f.name
 	}
 
-	if f.pool, err = dockertest.NewPool(""); err != nil {
+	f.endpoint = resolveEndpoint(f.endpoint)
+	if f.pool, err = dockertest.NewPool(f.endpoint); err != nil {
 		return err
 	}
 
 	if err := f.pool.Client.Ping(); err != nil {
 		return fmt.Errorf("docker unavailable: %w", err)
 	}
+	f.engine = detectEngine(f.pool.Client)
 
 	if f.network, err = f.getOrCreateNetwork(); err != nil {
 		return err
 	}
 
+	if f.reaper {
+		if f.reaperConn, err = f.startReaper(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// RunWithOptions starts a container, labelled so that it can be reaped if this process dies.
+func (f *Docker) RunWithOptions(opts *dockertest.RunOptions, hcOpts ...func(*docker.HostConfig)) (*dockertest.Resource, error) {
+	o := *opts
+	o.Labels = f.labels(opts.Labels)
+	return f.Pool().RunWithOptions(&o, hcOpts...)
+}
+
+// TearDown waits for pending purges and removes the network, if it was created by this fixture.
+// Every container which failed to purge is reported as a *PurgeError in the returned Errors.
 func (f *Docker) TearDown(context.Context) error {
-	if !f.networkExisted {
+	f.Wait()
+	f.mu.Lock()
+	errs := f.purgeErrs
+	f.purgeErrs = nil
+	f.mu.Unlock()
+	if !f.networkExisted && f.network != nil {
 		if err := f.Network().Close(); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("failed to remove network '%v': %w", f.networkName, err))
 		}
 	}
-	return nil
+	if f.reaperConn != nil {
+		// The reaper removes anything left behind shortly after the connection closes.
+		f.reaperConn.Close()
+		f.reaperConn = nil
+	}
+	return errs.errorOrNil()
 }
 
 func (f *Docker) getOrCreateNetwork() (*dockertest.Network, error) {
@@ -143,7 +217,9 @@ func (f *Docker) getOrCreateNetwork() (*dockertest.Network, error) {
 		return &dockertest.Network{Network: &ns[0]}, nil
 	}
 
-	nw, err := f.Pool().CreateNetwork(f.name)
+	nw, err := f.Pool().CreateNetwork(f.name, func(opts *docker.CreateNetworkOptions) {
+		opts.Labels = f.labels(nil)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker network: %w", err)
 	}
@@ -199,6 +275,38 @@ func ContainerTcpPort(resource *dockertest.Resource, network *dockertest.Network
 	return resource.GetPort(fmt.Sprintf("%s/tcp", port))
 }
 
+// ContainerAddress returns the address at which the tests can reach the container. Containers on a remote daemon
+// are reached at the daemon's host, and under podman the host container reaches them through
+// host.containers.internal. Otherwise, see the package-level ContainerAddress.
+func (f *Docker) ContainerAddress(resource *dockertest.Resource) string {
+	if UseBridgeNetwork(f.network) {
+		return HostIP(resource, f.network)
+	}
+	if host := remoteHost(f.endpoint); host != "" {
+		return host
+	}
+	if f.engine == EnginePodman && IsRunningInContainer() {
+		return "host.containers.internal"
+	}
+	return ContainerAddress(resource, f.network)
+}
+
+// hostAddress returns the address at which the tests can reach the ports a container publishes on its host.
+func (f *Docker) hostAddress(resource *dockertest.Resource) string {
+	if host := remoteHost(f.endpoint); host != "" {
+		return host
+	}
+	if IsRunningInContainer() {
+		if f.engine == EnginePodman {
+			return "host.containers.internal"
+		}
+		if gw := resource.Container.NetworkSettings.Gateway; gw != "" {
+			return gw
+		}
+	}
+	return "localhost"
+}
+
 func UseBridgeNetwork(network *dockertest.Network) bool {
 	// Check if there is a connected container that matches the hostname, which means the host
 	// container is connected to the network
@@ -214,21 +322,14 @@ func UseBridgeNetwork(network *dockertest.Network) bool {
 	return false
 }
 
-// IsRunningInContainer checks if the current executable is running inside a container.
-// This implementation is currently docker-specific and won't work on other container engines, such as podman.
-// A more portable solution is probably more ideal.
+// IsRunningInContainer checks if the current executable is running inside a container. See ContainerRuntime.
 func IsRunningInContainer() bool {
-	if _, err := os.Stat("/.dockerenv"); err == nil {
-		return true
-	} else if errors.Is(err, os.ErrNotExist) {
-		return false
-	} else {
-		panic(fmt.Errorf("error detecting if running inside container: %w", err))
-	}
+	return ContainerRuntime() != ""
 }
 
+// IsDockerRunning checks if a docker or podman daemon is reachable at the default endpoint.
 func IsDockerRunning() bool {
-	pool, err := dockertest.NewPool("")
+	pool, err := dockertest.NewPool(resolveEndpoint(""))
 	if err != nil {
 		return false
 	}
@@ -256,14 +357,25 @@ func getLogs(log *zap.Logger, containerID string, pool *dockertest.Pool) string
 	return buf.String()
 }
 
+// Purge removes a container in the background. Failures are reported by TearDown.
 func (f *Docker) Purge(r *dockertest.Resource) {
-	wg.Add(1)
+	f.purges.Add(1)
 	go func() {
-		f.Pool().Purge(r)
-		wg.Done()
+		defer f.purges.Done()
+		if err := f.Pool().Purge(r); err != nil {
+			f.log.Warn("failed to purge container", zap.String("container", HostName(r)), zap.Error(err))
+			f.mu.Lock()
+			f.purgeErrs = append(f.purgeErrs, &PurgeError{Container: HostName(r), ID: r.Container.ID, Err: err})
+			f.mu.Unlock()
+		}
 	}()
 }
 
+// Wait blocks until every container purged by this fixture has been removed.
+func (f *Docker) Wait() {
+	f.purges.Wait()
+}
+
 // Deprecated: use Name()
 func (f *Docker) GetName() string {
 	return f.name
@@ -0,0 +1,134 @@
+package fixtures
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Container engines the Docker fixture can talk to.
+const (
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+)
+
+// resolveEndpoint returns the daemon endpoint to connect to. An explicit endpoint wins, then DOCKER_HOST, then the
+// first docker or podman socket found. If none is found, "" lets dockertest choose its default.
+func resolveEndpoint(endpoint string) string {
+	if endpoint != "" {
+		return endpoint
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+	for _, path := range socketCandidates() {
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return "unix://" + path
+		}
+	}
+	return ""
+}
+
+// socketCandidates lists where docker and podman put their API sockets, rootful before rootless.
+func socketCandidates() []string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%v", os.Getuid())
+	}
+	candidates := []string{
+		"/var/run/docker.sock",
+		"/run/podman/podman.sock",
+		filepath.Join(runtimeDir, "docker.sock"),
+		filepath.Join(runtimeDir, "podman", "podman.sock"),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		// Docker Desktop
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	return candidates
+}
+
+// socketPath returns the path of a unix socket endpoint, or "" for any other kind of endpoint.
+func socketPath(endpoint string) string {
+	if strings.HasPrefix(endpoint, "unix://") {
+		return strings.TrimPrefix(endpoint, "unix://")
+	}
+	return ""
+}
+
+// remoteHost returns the host of a TCP endpoint, which is where the daemon publishes container ports.
+// It returns "" for local endpoints.
+func remoteHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "tcp" && u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// detectEngine asks the daemon whether it is docker or podman. Podman's docker-compatible API reports a
+// "Podman Engine" component.
+func detectEngine(client *docker.Client) string {
+	v, err := client.Version()
+	if err == nil && strings.Contains(v.Get("Components"), "Podman") {
+		return EnginePodman
+	}
+	return EngineDocker
+}
+
+var (
+	runtimeOnce sync.Once
+	runtimeName string
+)
+
+// ContainerRuntime returns the runtime the current executable is running inside of, such as "docker", "podman",
+// "kubernetes", "containerd" or "lxc". It returns "" when not running inside a container.
+func ContainerRuntime() string {
+	runtimeOnce.Do(func() {
+		runtimeName = detectContainerRuntime("/")
+	})
+	return runtimeName
+}
+
+// detectContainerRuntime inspects the marker files engines create and the cgroups of the init process, relative
+// to root.
+func detectContainerRuntime(root string) string {
+	if _, err := os.Stat(filepath.Join(root, ".dockerenv")); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat(filepath.Join(root, "run", ".containerenv")); err == nil {
+		return "podman"
+	}
+	file, err := os.Open(filepath.Join(root, "proc", "1", "cgroup"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	markers := []struct{ marker, runtime string }{
+		{"kubepods", "kubernetes"},
+		{"libpod", "podman"},
+		{"docker", "docker"},
+		{"containerd", "containerd"},
+		{"lxc", "lxc"},
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		for _, m := range markers {
+			if strings.Contains(scanner.Text(), m.marker) {
+				return m.runtime
+			}
+		}
+	}
+	return ""
+}
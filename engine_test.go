@@ -0,0 +1,63 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.2:2375")
+	assert.Equal(t, "unix:///run/podman/podman.sock", resolveEndpoint("unix:///run/podman/podman.sock"))
+	assert.Equal(t, "tcp://10.0.0.2:2375", resolveEndpoint(""))
+}
+
+func TestSocketCandidates(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	candidates := socketCandidates()
+	assert.Contains(t, candidates, "/run/user/1000/podman/podman.sock")
+	assert.Contains(t, candidates, "/run/user/1000/docker.sock")
+	assert.Contains(t, candidates, "/run/podman/podman.sock")
+}
+
+func TestEndpointHosts(t *testing.T) {
+	assert.Equal(t, "/run/podman/podman.sock", socketPath("unix:///run/podman/podman.sock"))
+	assert.Equal(t, "", socketPath("tcp://10.0.0.2:2375"))
+
+	assert.Equal(t, "10.0.0.2", remoteHost("tcp://10.0.0.2:2375"))
+	assert.Equal(t, "docker.example.com", remoteHost("https://docker.example.com:2376"))
+	assert.Equal(t, "", remoteHost("tcp://127.0.0.1:2375"))
+	assert.Equal(t, "", remoteHost("tcp://localhost:2375"))
+	assert.Equal(t, "", remoteHost("unix:///var/run/docker.sock"))
+	assert.Equal(t, "", remoteHost(""))
+}
+
+func TestDetectContainerRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		runtime string
+	}{
+		{name: "Host", files: map[string]string{"proc/1/cgroup": "0::/init.scope\n"}, runtime: ""},
+		{name: "NoProc", runtime: ""},
+		{name: "DockerEnv", files: map[string]string{".dockerenv": ""}, runtime: "docker"},
+		{name: "ContainerEnv", files: map[string]string{"run/.containerenv": ""}, runtime: "podman"},
+		{name: "DockerCgroup", files: map[string]string{"proc/1/cgroup": "12:memory:/docker/0123abcd\n"}, runtime: "docker"},
+		{name: "PodmanCgroup", files: map[string]string{"proc/1/cgroup": "0::/machine.slice/libpod-0123abcd.scope\n"}, runtime: "podman"},
+		{name: "KubernetesCgroup", files: map[string]string{"proc/1/cgroup": "0::/kubepods/besteffort/pod1234/0123abcd\n"}, runtime: "kubernetes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			}
+			assert.Equal(t, tt.runtime, detectContainerRuntime(root))
+		})
+	}
+}
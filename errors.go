@@ -0,0 +1,130 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// FixtureError is returned when a fixture fails to set up or tear down.
+type FixtureError struct {
+	Name string
+	Type string
+	// Op is OpSetUp or OpTearDown.
+	Op  string
+	Err error
+}
+
+func (e *FixtureError) Error() string {
+	return fmt.Sprintf("failed to %v fixture '%v' (%v): %v", e.Op, e.Name, e.Type, e.Err)
+}
+
+func (e *FixtureError) Unwrap() error {
+	return e.Err
+}
+
+// PurgeError is returned when a container could not be removed.
+type PurgeError struct {
+	Container string
+	ID        string
+	Err       error
+}
+
+func (e *PurgeError) Error() string {
+	return fmt.Sprintf("failed to purge container '%v' (%v): %v", e.Container, e.ID, e.Err)
+}
+
+func (e *PurgeError) Unwrap() error {
+	return e.Err
+}
+
+// ExecError is returned when a command run inside a container exits with a non-zero status.
+type ExecError struct {
+	Cmd      []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("'%v' exited with status %v: %v", strings.Join(e.Cmd, " "), e.ExitCode, strings.TrimSpace(e.Stderr))
+}
+
+// RetryError is returned when a fixture failed to set up on every attempt.
+type RetryError struct {
+	Attempts Errors
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("gave up after %v attempts: %v", len(e.Attempts), e.Attempts)
+}
+
+// Unwrap returns the error from the last attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// TimeoutError is returned when a fixture exceeds its setup or teardown timeout.
+type TimeoutError struct {
+	Name    string
+	Type    string
+	Op      string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("fixture '%v' (%v) timed out after %v during %v: %v", e.Name, e.Type, e.Timeout, e.Op, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Errors aggregates the errors of fixtures which failed independently of each other.
+type Errors []error
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "\t* " + err.Error()
+	}
+	return fmt.Sprintf("%v errors occurred:\n%v", len(e), strings.Join(msgs, "\n"))
+}
+
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Is reports whether any of the aggregated errors matches target.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first aggregated error which matches target.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorOrNil returns nil rather than an empty Errors, which would otherwise be a non-nil error.
+func (e Errors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -11,14 +11,11 @@ import (
 var f *fixtures.Fixtures
 
 func TestMain(m *testing.M) {
-	ctx := context.Background()
-	f = fixtures.NewFixtures()
-	f.Add(ctx, fixtures.NewDocker())
-	f.Add(ctx, fixtures.NewPostgres(f.Docker()))
-	defer f.RecoverTearDown(ctx)
-	status := m.Run()
-	f.TearDown(ctx)
-	os.Exit(status)
+	os.Exit(fixtures.RunMain(m, func(ctx context.Context, fx *fixtures.Fixtures) error {
+		f = fx
+		d := fixtures.NewDocker()
+		return f.Add(ctx, d, fixtures.NewPostgres(d))
+	}))
 }
 
 func TestExample(t *testing.T) {
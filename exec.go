@@ -0,0 +1,67 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// ExecOptions configures a command run inside a container with Docker.Exec.
+type ExecOptions struct {
+	// Stdin is copied to the command's standard input, which is closed once Stdin is exhausted.
+	Stdin io.Reader
+	// Env is added to the container's environment, formatted as "KEY=value".
+	Env []string
+	// WorkDir runs the command in this directory. The container must have a shell.
+	WorkDir string
+	// User runs the command as this user or uid instead of the container's default user.
+	User string
+}
+
+// ExecResult is the outcome of a command run with Docker.Exec.
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Exec runs a command inside a running container and waits for it to exit. A non-zero exit code is not an error;
+// check ExecResult.ExitCode.
+func (f *Docker) Exec(ctx context.Context, resource *dockertest.Resource, cmd []string, opts ExecOptions) (ExecResult, error) {
+	if opts.WorkDir != "" {
+		// The exec API in use predates WorkingDir, so change directory in a shell.
+		cmd = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, opts.WorkDir}, cmd...)
+	}
+	client := f.Pool().Client
+	exec, err := client.CreateExec(docker.CreateExecOptions{
+		Context:      ctx,
+		Container:    resource.Container.ID,
+		Cmd:          cmd,
+		Env:          opts.Env,
+		User:         opts.User,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create exec: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := client.StartExec(exec.ID, docker.StartExecOptions{
+		Context:      ctx,
+		InputStream:  opts.Stdin,
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+	}); err != nil {
+		return ExecResult{}, fmt.Errorf("failed to start exec: %w", err)
+	}
+	inspect, err := client.InspectExec(exec.ID)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return ExecResult{ExitCode: inspect.ExitCode, Stdout: stdout.String(), Stderr: stderr.String()}, nil
+}
@@ -0,0 +1,46 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerExec(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	c := NewContainer(d,
+		ContainerRepo("alpine"),
+		ContainerTag("3"),
+		ContainerCmd("sleep", "600"),
+	)
+	f.AddT(t, d, c)
+
+	res, err := d.Exec(ctx, c.Resource(), []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, ExecOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ExecResult{ExitCode: 3, Stdout: "out\n", Stderr: "err\n"}, res)
+
+	res, err = c.Exec(ctx, []string{"sh", "-c", `cat; echo "$GREETING"; pwd; id -u`}, ExecOptions{
+		Stdin:   strings.NewReader("stdin\n"),
+		Env:     []string{"GREETING=hello"},
+		WorkDir: "/tmp",
+		User:    "nobody",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.ExitCode)
+	assert.Equal(t, "stdin\nhello\n/tmp\n65534\n", res.Stdout)
+}
+
+func TestExecError(t *testing.T) {
+	var err error = &ExecError{Cmd: []string{"createdb", "test"}, ExitCode: 1, Stderr: "database exists\n"}
+	assert.EqualError(t, err, "'createdb test' exited with status 1: database exists")
+	var execErr *ExecError
+	assert.True(t, errors.As(err, &execErr))
+}
@@ -17,3 +17,9 @@ type Fixture interface {
 	SetUp(context.Context) error
 	TearDown(context.Context) error
 }
+
+// Dependent is implemented by fixtures which must be set up after (and torn down before) other fixtures.
+// Dependencies which have not been added to Fixtures are assumed to be managed by the caller.
+type Dependent interface {
+	Dependencies() []Fixture
+}
@@ -2,9 +2,15 @@ package fixtures
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type DummyFixture struct {
@@ -46,3 +52,253 @@ func TestFixtures(t *testing.T) {
 	fixtures.TearDown(ctx)
 	assert.Equal(t, 0, f.DummyMember)
 }
+
+type recordingFixture struct {
+	BaseFixture
+	name   string
+	deps   []Fixture
+	events *[]string
+}
+
+func (rf *recordingFixture) Dependencies() []Fixture {
+	return rf.deps
+}
+
+func (rf *recordingFixture) SetUp(ctx context.Context) error {
+	*rf.events = append(*rf.events, "setup "+rf.name)
+	return nil
+}
+
+func (rf *recordingFixture) TearDown(context.Context) error {
+	*rf.events = append(*rf.events, "teardown "+rf.name)
+	return nil
+}
+
+func TestFixturesDependencyOrder(t *testing.T) {
+	ctx := context.Background()
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+	network := &recordingFixture{name: "network", events: &events}
+	pg := &recordingFixture{name: "postgres", deps: []Fixture{docker}, events: &events}
+	app := &recordingFixture{name: "app", events: &events}
+
+	fixtures := NewFixtures()
+	require.NoError(t, fixtures.RegisterByName("app", app, FixtureDependsOn(pg, network)))
+	require.NoError(t, fixtures.RegisterByName("postgres", pg))
+	require.NoError(t, fixtures.RegisterByName("network", network))
+	require.NoError(t, fixtures.RegisterByName("docker", docker))
+
+	require.NoError(t, fixtures.SetUp(ctx))
+	assert.Equal(t, []string{"setup docker", "setup postgres", "setup network", "setup app"}, events)
+
+	// Already set up fixtures are skipped.
+	require.NoError(t, fixtures.SetUp(ctx))
+	assert.Len(t, events, 4)
+
+	events = events[:0]
+	require.NoError(t, fixtures.TearDown(ctx))
+	assert.Equal(t, []string{"teardown app", "teardown network", "teardown postgres", "teardown docker"}, events)
+}
+
+func TestFixturesAddSetsUpDependencies(t *testing.T) {
+	ctx := context.Background()
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+	pg := &recordingFixture{name: "postgres", deps: []Fixture{docker}, events: &events}
+
+	fixtures := NewFixtures()
+	require.NoError(t, fixtures.Register(docker))
+	require.NoError(t, fixtures.Add(ctx, pg))
+	assert.Equal(t, []string{"setup docker", "setup postgres"}, events)
+}
+
+func TestFixturesDependencyCycle(t *testing.T) {
+	events := []string{}
+	a := &recordingFixture{name: "a", events: &events}
+	b := &recordingFixture{name: "b", deps: []Fixture{a}, events: &events}
+
+	fixtures := NewFixtures()
+	require.NoError(t, fixtures.RegisterByName("b", b))
+	err := fixtures.RegisterByName("a", a, FixtureDependsOn(b))
+	require.ErrorIs(t, err, ErrDependencyCycle)
+	assert.Contains(t, err.Error(), "b -> a -> b")
+	assert.Nil(t, fixtures.Get("a"))
+}
+
+type slowFixture struct {
+	BaseFixture
+	delay    time.Duration
+	err      error
+	deps     []Fixture
+	running  *int32
+	peak     *int32
+	torndown bool
+}
+
+func (sf *slowFixture) Dependencies() []Fixture {
+	return sf.deps
+}
+
+func (sf *slowFixture) SetUp(ctx context.Context) error {
+	n := atomic.AddInt32(sf.running, 1)
+	defer atomic.AddInt32(sf.running, -1)
+	for {
+		peak := atomic.LoadInt32(sf.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(sf.peak, peak, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(sf.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return sf.err
+}
+
+func (sf *slowFixture) TearDown(context.Context) error {
+	sf.torndown = true
+	return nil
+}
+
+func TestFixturesSetUpParallel(t *testing.T) {
+	ctx := context.Background()
+	var running, peak int32
+	docker := &slowFixture{delay: 10 * time.Millisecond, running: &running, peak: &peak}
+	fixtures := NewFixtures(FixturesConcurrency(2))
+	require.NoError(t, fixtures.Register(docker))
+	for i := 0; i < 4; i++ {
+		require.NoError(t, fixtures.Register(&slowFixture{delay: 50 * time.Millisecond, deps: []Fixture{docker}, running: &running, peak: &peak}))
+	}
+	require.NoError(t, fixtures.SetUpParallel(ctx))
+	assert.Equal(t, int32(2), peak)
+	require.NoError(t, fixtures.TearDown(ctx))
+}
+
+func TestFixturesSetUpParallelFailure(t *testing.T) {
+	ctx := context.Background()
+	var running, peak int32
+	errBoom := errors.New("boom")
+	docker := &slowFixture{running: &running, peak: &peak}
+	ok := &slowFixture{deps: []Fixture{docker}, running: &running, peak: &peak}
+	failed := &slowFixture{delay: 10 * time.Millisecond, err: errBoom, deps: []Fixture{docker}, running: &running, peak: &peak}
+	hung := &slowFixture{delay: time.Minute, deps: []Fixture{docker}, running: &running, peak: &peak}
+	never := &slowFixture{deps: []Fixture{failed}, running: &running, peak: &peak}
+
+	fixtures := NewFixtures()
+	require.NoError(t, fixtures.Register(docker, ok, failed, hung, never))
+	err := fixtures.SetUpParallel(ctx)
+	require.ErrorIs(t, err, errBoom)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.True(t, docker.torndown)
+	assert.True(t, ok.torndown)
+	assert.True(t, failed.torndown)
+	assert.True(t, hung.torndown)
+	assert.False(t, never.torndown)
+}
+
+func TestFixturesLookup(t *testing.T) {
+	ctx := context.Background()
+	events := []string{}
+	fixtures := NewFixtures()
+	df := &DummyFixture{}
+	r1 := &recordingFixture{name: "r1", events: &events}
+	r2 := &recordingFixture{name: "r2", events: &events}
+	require.NoError(t, fixtures.AddByName(ctx, "r1", r1))
+	require.NoError(t, fixtures.AddByName(ctx, "dummy", df))
+	require.NoError(t, fixtures.AddByName(ctx, "r2", r2))
+
+	d, err := Get[*DummyFixture](fixtures, "dummy")
+	require.NoError(t, err)
+	assert.Same(t, df, d)
+
+	_, err = Get[*DummyFixture](fixtures, "r1")
+	assert.ErrorIs(t, err, ErrFixtureNotFound)
+	_, err = Get[*DummyFixture](fixtures, "missing")
+	assert.ErrorIs(t, err, ErrFixtureNotFound)
+
+	for i := 0; i < 10; i++ {
+		r, err := First[*recordingFixture](fixtures)
+		require.NoError(t, err)
+		assert.Same(t, r1, r)
+	}
+	_, err = First[*Docker](fixtures)
+	assert.ErrorIs(t, err, ErrFixtureNotFound)
+
+	assert.Equal(t, []*recordingFixture{r1, r2}, All[*recordingFixture](fixtures))
+	assert.Empty(t, All[*Postgres](fixtures))
+	assert.Panics(t, func() { fixtures.Docker() })
+}
+
+type failingFixture struct {
+	BaseFixture
+	err error
+}
+
+func (ff *failingFixture) SetUp(context.Context) error {
+	return nil
+}
+
+func (ff *failingFixture) TearDown(context.Context) error {
+	return ff.err
+}
+
+func TestFixturesTearDownErrors(t *testing.T) {
+	ctx := context.Background()
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	fixtures := NewFixtures()
+	require.NoError(t, fixtures.AddByName(ctx, "first", &failingFixture{err: errFirst}))
+	require.NoError(t, fixtures.AddByName(ctx, "ok", &DummyFixture{}))
+	require.NoError(t, fixtures.AddByName(ctx, "second", &failingFixture{err: errSecond}))
+
+	err := fixtures.TearDown(ctx)
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, err, errFirst)
+	assert.ErrorIs(t, err, errSecond)
+
+	var fixtureErr *FixtureError
+	require.ErrorAs(t, errs[0], &fixtureErr)
+	assert.Equal(t, "second", fixtureErr.Name)
+	assert.Equal(t, "fixtures.failingFixture", fixtureErr.Type)
+	assert.Equal(t, OpTearDown, fixtureErr.Op)
+	assert.Contains(t, err.Error(), "failed to teardown fixture 'first' (fixtures.failingFixture): first")
+}
+
+func TestFixturesParallelTests(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			f := New(t)
+			df := &DummyFixture{}
+			f.AddT(t, df, &DummyFixture{})
+			assert.Equal(t, 123, df.DummyMember)
+			assert.Len(t, All[*DummyFixture](f), 2)
+		})
+	}
+}
+
+func TestFixturesConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	fixtures := NewFixtures()
+	var running, peak int32
+	shared := &slowFixture{delay: 5 * time.Millisecond, running: &running, peak: &peak}
+	require.NoError(t, fixtures.Register(shared))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, fixtures.Add(ctx, &slowFixture{deps: []Fixture{shared}, running: &running, peak: &peak}))
+			_, err := First[*slowFixture](fixtures)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, All[*slowFixture](fixtures), 9)
+	require.NoError(t, fixtures.TearDown(ctx))
+	assert.True(t, shared.torndown)
+}
@@ -3,14 +3,12 @@ package fixtures
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
 
-var wg sync.WaitGroup
-
 type FixturesOpt func(*Fixtures)
 
 func NewFixtures(opts ...FixturesOpt) *Fixtures {
@@ -30,12 +28,101 @@ func FixturesLogger(logger *zap.Logger) FixturesOpt {
 	}
 }
 
+// FixturesConcurrency limits how many fixtures SetUpParallel sets up at once. Defaults to unlimited.
+func FixturesConcurrency(concurrency int) FixturesOpt {
+	return func(f *Fixtures) {
+		f.concurrency = concurrency
+	}
+}
+
+// FixturesSetUpTimeout bounds how long each fixture may take to set up, unless overridden by FixtureSetUpTimeout.
+func FixturesSetUpTimeout(timeout time.Duration) FixturesOpt {
+	return func(f *Fixtures) {
+		f.setUpTimeout = timeout
+	}
+}
+
+// FixturesTearDownTimeout bounds how long each fixture may take to tear down, unless overridden by
+// FixtureTearDownTimeout.
+func FixturesTearDownTimeout(timeout time.Duration) FixturesOpt {
+	return func(f *Fixtures) {
+		f.tearDownTimeout = timeout
+	}
+}
+
+// FixtureConfig holds the options a fixture was added with.
+type FixtureConfig struct {
+	dependsOn       []Fixture
+	setUpTimeout    time.Duration
+	tearDownTimeout time.Duration
+	retry           *RetryPolicy
+}
+
+type FixtureOpt func(*FixtureConfig)
+
+// FixtureDependsOn declares that a fixture must be set up after the given fixtures, in addition to any
+// dependencies it reports through the Dependent interface.
+func FixtureDependsOn(dependencies ...Fixture) FixtureOpt {
+	return func(c *FixtureConfig) {
+		c.dependsOn = append(c.dependsOn, dependencies...)
+	}
+}
+
+// FixtureSetUpTimeout bounds how long this fixture may take to set up.
+func FixtureSetUpTimeout(timeout time.Duration) FixtureOpt {
+	return func(c *FixtureConfig) {
+		c.setUpTimeout = timeout
+	}
+}
+
+// FixtureTearDownTimeout bounds how long this fixture may take to tear down.
+func FixtureTearDownTimeout(timeout time.Duration) FixtureOpt {
+	return func(c *FixtureConfig) {
+		c.tearDownTimeout = timeout
+	}
+}
+
+// FixtureRetry retries SetUp according to policy, tearing down the partially created fixture between attempts.
+func FixtureRetry(policy RetryPolicy) FixtureOpt {
+	return func(c *FixtureConfig) {
+		c.retry = &policy
+	}
+}
+
+type entry struct {
+	name    string
+	fixture Fixture
+	config  FixtureConfig
+	// mu is held while the fixture is being set up or torn down.
+	mu sync.Mutex
+	// setUp is true once SetUp has been attempted, so that partially created fixtures are still torn down.
+	setUp bool
+	// logsReported is true once the fixture's output has been reported for the current setup.
+	logsReported bool
+	// abandoned is set while a SetUp or TearDown which timed out is still running.
+	abandoned *abandonedCall
+}
+
+func (e *entry) isSetUp() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.setUp
+}
+
+// Fixtures is a registry of fixtures. It is safe for concurrent use.
 type Fixtures struct {
-	log   *zap.Logger
-	store map[string]Fixture
-	order []string
+	log *zap.Logger
+	// mu guards store and order. It is never held while a fixture is being set up or torn down.
+	mu              sync.Mutex
+	store           map[string]*entry
+	order           []string
+	concurrency     int
+	hooks           []Hook
+	setUpTimeout    time.Duration
+	tearDownTimeout time.Duration
 }
 
+// Add registers and sets up each fixture under a random name.
 func (f *Fixtures) Add(ctx context.Context, fixtures ...Fixture) error {
 	for _, fix := range fixtures {
 		if err := f.AddByName(ctx, GetRandomName(0), fix); err != nil {
@@ -45,57 +132,253 @@ func (f *Fixtures) Add(ctx context.Context, fixtures ...Fixture) error {
 	return nil
 }
 
-func (f *Fixtures) AddByName(ctx context.Context, name string, fixture Fixture) error {
+// AddByName registers a fixture and sets it up, along with any registered dependencies which are not yet set up.
+func (f *Fixtures) AddByName(ctx context.Context, name string, fixture Fixture, opts ...FixtureOpt) error {
+	if err := f.RegisterByName(name, fixture, opts...); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	required := f.requires(name)
+	entries, err := f.sortedEntries(func(e *entry) bool { return required[e.name] })
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := f.setUp(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register adds fixtures under random names without setting them up. Call SetUp to set them up.
+func (f *Fixtures) Register(fixtures ...Fixture) error {
+	for _, fix := range fixtures {
+		if err := f.RegisterByName(GetRandomName(0), fix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterByName adds a fixture without setting it up. Call SetUp to set it up.
+func (f *Fixtures) RegisterByName(name string, fixture Fixture, opts ...FixtureOpt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.store == nil {
 		f.order = []string{}
-		f.store = map[string]Fixture{}
+		f.store = map[string]*entry{}
+	}
+	if _, ok := f.store[name]; ok {
+		return fmt.Errorf("fixture '%v' already exists", name)
+	}
+	e := &entry{
+		name:    name,
+		fixture: fixture,
+	}
+	for _, opt := range opts {
+		opt(&e.config)
 	}
 	f.order = append(f.order, name)
-	f.store[name] = fixture
-	err := fixture.SetUp(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to setup fixture '%v': %w", name, err)
+	f.store[name] = e
+	if _, err := f.sorted(); err != nil {
+		f.remove(name)
+		return fmt.Errorf("failed to add fixture '%v': %w", name, err)
 	}
-	f.log.Debug("setup", zap.String("type", fmt.Sprint(reflect.TypeOf(fixture).Elem())), zap.String("name", name))
-	return err
+	return nil
 }
 
 func (f *Fixtures) Get(name string) Fixture {
-	return f.store[name]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.store[name]; ok {
+		return e.fixture
+	}
+	return nil
 }
 
+// SetUp sets up every registered fixture which is not already set up, in dependency order.
 func (f *Fixtures) SetUp(ctx context.Context) error {
-	var err error
-	for name, fixture := range f.store {
-		err = fixture.SetUp(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to setup fixture '%v': %w", name, err)
+	f.mu.Lock()
+	entries, err := f.sortedEntries(nil)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := f.setUp(ctx, e); err != nil {
+			return err
 		}
-		f.log.Debug("setup", zap.String("type", fmt.Sprint(reflect.TypeOf(fixture).Elem())), zap.String("name", name))
 	}
-	return err
+	return nil
 }
 
+func (f *Fixtures) setUp(ctx context.Context, e *entry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.setUp {
+		return nil
+	}
+	e.setUp = true
+	e.logsReported = false
+	if err := f.setUpWithRetry(ctx, e); err != nil {
+		if tail := e.logTail(); tail != "" {
+			f.log.Error(tail)
+		}
+		return &FixtureError{Name: e.name, Type: fixtureType(e.fixture), Op: OpSetUp, Err: err}
+	}
+	f.log.Debug("setup", zap.String("type", fixtureType(e.fixture)), zap.String("name", e.name))
+	return nil
+}
+
+// SetUpParallel sets up every registered fixture which is not already set up. Fixtures on independent branches
+// of the dependency graph are set up concurrently, limited by FixturesConcurrency.
+// If any fixture fails, the remaining fixtures are not started, every fixture started by this call is torn
+// down, and all errors are returned.
+func (f *Fixtures) SetUpParallel(ctx context.Context) error {
+	f.mu.Lock()
+	entries, err := f.sortedEntries(nil)
+	g := f.graph()
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	limit := f.concurrency
+	if limit <= 0 {
+		limit = len(entries)
+	}
+
+	setUpCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+	done := map[string]bool{}
+	started := map[string]bool{}
+	for _, e := range entries {
+		if e.isSetUp() {
+			done[e.name] = true
+		}
+	}
+
+	var errs Errors
+	running := 0
+	for {
+		for _, e := range entries {
+			if len(errs) > 0 || running >= limit {
+				break
+			}
+			if done[e.name] || started[e.name] || !allDone(g[e.name], done) {
+				continue
+			}
+			started[e.name] = true
+			running++
+			go func(e *entry) {
+				results <- result{name: e.name, err: f.setUp(setUpCtx, e)}
+			}(e)
+		}
+		if running == 0 {
+			break
+		}
+		r := <-results
+		running--
+		if r.err != nil {
+			errs = append(errs, r.err)
+			cancel()
+			continue
+		}
+		done[r.name] = true
+	}
+
+	if len(errs) > 0 {
+		var torndown []*entry
+		for _, e := range entries {
+			if started[e.name] {
+				torndown = append(torndown, e)
+			}
+		}
+		errs = append(errs, f.tearDown(ctx, torndown)...)
+	}
+	return errs.errorOrNil()
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// TearDown tears down every fixture which was set up, in reverse dependency order. Teardown continues past
+// failures; every failed fixture is reported as a *FixtureError in the returned Errors.
 func (f *Fixtures) TearDown(ctx context.Context) error {
-	fixtureNames := []string{}
-	for _, name := range f.order {
-		fixtureNames = append([]string{name}, fixtureNames...)
+	f.mu.Lock()
+	entries := f.tearDownOrder(nil)
+	f.mu.Unlock()
+	return f.tearDown(ctx, entries).errorOrNil()
+}
+
+// tearDownOrder returns the selected fixtures in topological order. It must be called with f.mu held.
+func (f *Fixtures) tearDownOrder(selected func(*entry) bool) []*entry {
+	entries, err := f.sortedEntries(selected)
+	if err != nil {
+		// The graph was valid when each fixture was added, so this only happens if a fixture changed its
+		// dependencies afterwards. Fall back to insertion order.
+		f.log.Warn("failed to resolve fixture dependencies", zap.Error(err))
+		entries = []*entry{}
+		for _, name := range f.order {
+			if e := f.store[name]; selected == nil || selected(e) {
+				entries = append(entries, e)
+			}
+		}
 	}
-	var firstErr error
-	for _, name := range fixtureNames {
-		fixture := f.Get(name)
-		err := fixture.TearDown(ctx)
+	return entries
+}
+
+// tearDown tears down the fixtures which were set up, in the reverse of the given order, then waits for any
+// containers they purged in the background.
+func (f *Fixtures) tearDown(ctx context.Context, entries []*entry) Errors {
+	var errs Errors
+	dockers := []*Docker{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		torndown, err := f.tearDownEntry(ctx, e)
+		if !torndown {
+			continue
+		}
 		if err != nil {
-			f.log.Warn("failed to teardown fixture", zap.String("fixture", name), zap.Error(err))
-			if firstErr == nil {
-				firstErr = err
+			f.log.Warn("failed to teardown fixture", zap.String("fixture", e.name), zap.Error(err))
+			errs = append(errs, &FixtureError{Name: e.name, Type: fixtureType(e.fixture), Op: OpTearDown, Err: err})
+		}
+		f.log.Debug("teardown", zap.String("type", fixtureType(e.fixture)), zap.String("name", e.name))
+		if d, ok := e.fixture.(Dependent); ok {
+			for _, dep := range d.Dependencies() {
+				if docker, ok := dep.(*Docker); ok {
+					dockers = append(dockers, docker)
+				}
 			}
 		}
-		f.log.Debug("teardown", zap.String("type", fmt.Sprint(reflect.TypeOf(fixture).Elem())), zap.String("name", name))
 	}
+	for _, d := range dockers {
+		d.Wait()
+	}
+	return errs
+}
 
-	wg.Wait()
-	return firstErr
+func (f *Fixtures) tearDownEntry(ctx context.Context, e *entry) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.setUp {
+		return false, nil
+	}
+	e.setUp = false
+	return true, f.run(ctx, e, OpTearDown, e.fixture.TearDown)
 }
 
 // RecoverTearDown returns a deferrable function that will teardown in the event of a panic.
@@ -108,22 +391,73 @@ func (f *Fixtures) RecoverTearDown(ctx context.Context) {
 	}
 }
 
+// remove unregisters a fixture. It must be called with f.mu held.
+func (f *Fixtures) remove(name string) {
+	delete(f.store, name)
+	for i, n := range f.order {
+		if n == name {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get returns the fixture registered under name, if it is a T.
+func Get[T Fixture](f *Fixtures, name string) (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var zero T
+	e, ok := f.store[name]
+	if !ok {
+		return zero, fmt.Errorf("%w: '%v'", ErrFixtureNotFound, name)
+	}
+	val, ok := e.fixture.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: '%v' is %T, not %T", ErrFixtureNotFound, name, e.fixture, zero)
+	}
+	return val, nil
+}
+
+// First returns the first fixture of type T, in the order fixtures were added.
+func First[T Fixture](f *Fixtures) (T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var zero T
+	for _, name := range f.order {
+		if val, ok := f.store[name].fixture.(T); ok {
+			return val, nil
+		}
+	}
+	return zero, fmt.Errorf("%w: no %T fixture", ErrFixtureNotFound, zero)
+}
+
+// All returns every fixture of type T, in the order fixtures were added.
+func All[T Fixture](f *Fixtures) []T {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := []T{}
+	for _, name := range f.order {
+		if val, ok := f.store[name].fixture.(T); ok {
+			result = append(result, val)
+		}
+	}
+	return result
+}
+
 // Docker() returns the first Docker fixture. If none exists, panic.
 func (f *Fixtures) Docker() *Docker {
-	for _, x := range f.store {
-		if val, ok := x.(*Docker); ok {
-			return val
-		}
+	d, err := First[*Docker](f)
+	if err != nil {
+		panic("no docker fixture found")
 	}
-	panic("no docker fixture found")
+	return d
 }
 
 // Postgres() returns the first Postgres fixture. If none exists, panic.
 func (f *Fixtures) Postgres() *Postgres {
-	for _, x := range f.store {
-		if val, ok := x.(*Postgres); ok {
-			return val
-		}
+	p, err := First[*Postgres](f)
+	if err != nil {
+		panic("no postgres fixture found")
 	}
-	panic("no postgres fixture found")
+	return p
 }
@@ -0,0 +1,151 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// The functions in this file read the registry and must be called with f.mu held.
+
+// dependencies returns the names of the registered fixtures which e depends on.
+// Dependencies which were never added to Fixtures are ignored.
+func (f *Fixtures) dependencies(e *entry, names map[Fixture]string) []string {
+	deps := e.config.dependsOn
+	if d, ok := e.fixture.(Dependent); ok {
+		deps = append(deps[:len(deps):len(deps)], d.Dependencies()...)
+	}
+	result := []string{}
+	for _, dep := range deps {
+		if dep == nil {
+			continue
+		}
+		if name, ok := names[dep]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// graph returns the dependencies of every registered fixture, keyed by name.
+func (f *Fixtures) graph() map[string][]string {
+	names := make(map[Fixture]string, len(f.order))
+	for _, name := range f.order {
+		names[f.store[name].fixture] = name
+	}
+	g := make(map[string][]string, len(f.order))
+	for _, name := range f.order {
+		g[name] = f.dependencies(f.store[name], names)
+	}
+	return g
+}
+
+// sorted returns the names of every registered fixture in topological order.
+// Fixtures which don't depend on each other keep their insertion order.
+func (f *Fixtures) sorted() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	g := f.graph()
+	state := make(map[string]int, len(f.order))
+	order := make([]string, 0, len(f.order))
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					cycle := append(path[i:len(path):len(path)], name)
+					return fmt.Errorf("%w: %v", ErrDependencyCycle, strings.Join(cycle, " -> "))
+				}
+			}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range g[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range f.order {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// sortedEntries returns the selected fixtures in topological order. A nil selected returns every fixture.
+func (f *Fixtures) sortedEntries(selected func(*entry) bool) ([]*entry, error) {
+	order, err := f.sorted()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*entry, 0, len(order))
+	for _, name := range order {
+		if e := f.store[name]; selected == nil || selected(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// requires returns the set containing name and every fixture it transitively depends on.
+func (f *Fixtures) requires(name string) map[string]bool {
+	g := f.graph()
+	required := map[string]bool{}
+	var visit func(string)
+	visit = func(n string) {
+		if required[n] {
+			return
+		}
+		required[n] = true
+		for _, dep := range g[n] {
+			visit(dep)
+		}
+	}
+	visit(name)
+	return required
+}
+
+// dependents returns the set containing names and every fixture which transitively depends on them.
+func (f *Fixtures) dependents(names ...string) map[string]bool {
+	g := f.graph()
+	reverse := map[string][]string{}
+	for _, name := range f.order {
+		for _, dep := range g[name] {
+			reverse[dep] = append(reverse[dep], name)
+		}
+	}
+	result := map[string]bool{}
+	var visit func(string)
+	visit = func(n string) {
+		if result[n] {
+			return
+		}
+		result[n] = true
+		for _, d := range reverse[n] {
+			visit(d)
+		}
+	}
+	for _, name := range names {
+		if _, ok := f.store[name]; ok {
+			visit(name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const (
+	OpSetUp    = "setup"
+	OpTearDown = "teardown"
+)
+
+// Event describes a lifecycle transition of a fixture.
+type Event struct {
+	// Name the fixture was added under.
+	Name string
+	// Type of the fixture, e.g. "fixtures.Postgres".
+	Type    string
+	Fixture Fixture
+	// Op is OpSetUp or OpTearDown.
+	Op string
+	// Duration of the SetUp or TearDown call. Zero for BeforeSetUp and BeforeTearDown.
+	Duration time.Duration
+	// Err returned by the fixture, if any.
+	Err error
+}
+
+// Hook observes the lifecycle of every fixture in Fixtures. Hooks may be called concurrently by SetUpParallel.
+// Embed NopHook to implement only the methods you need.
+type Hook interface {
+	BeforeSetUp(context.Context, Event)
+	AfterSetUp(context.Context, Event)
+	BeforeTearDown(context.Context, Event)
+	AfterTearDown(context.Context, Event)
+	// OnError is called after AfterSetUp or AfterTearDown when the fixture returned an error.
+	OnError(context.Context, Event)
+}
+
+// NopHook implements Hook and does nothing.
+type NopHook struct{}
+
+func (NopHook) BeforeSetUp(context.Context, Event)    {}
+func (NopHook) AfterSetUp(context.Context, Event)     {}
+func (NopHook) BeforeTearDown(context.Context, Event) {}
+func (NopHook) AfterTearDown(context.Context, Event)  {}
+func (NopHook) OnError(context.Context, Event)        {}
+
+// FixturesHook registers hooks which are notified as fixtures are set up and torn down.
+func FixturesHook(hooks ...Hook) FixturesOpt {
+	return func(f *Fixtures) {
+		f.hooks = append(f.hooks, hooks...)
+	}
+}
+
+// run calls fn, notifying hooks before and after.
+func (f *Fixtures) run(ctx context.Context, e *entry, op string, fn func(context.Context) error) error {
+	ev := Event{
+		Name:    e.name,
+		Type:    fixtureType(e.fixture),
+		Fixture: e.fixture,
+		Op:      op,
+	}
+	for _, h := range f.hooks {
+		if op == OpSetUp {
+			h.BeforeSetUp(ctx, ev)
+		} else {
+			h.BeforeTearDown(ctx, ev)
+		}
+	}
+	start := time.Now()
+	err := f.withTimeout(ctx, e, op, fn)
+	ev.Duration = time.Since(start)
+	ev.Err = err
+	for _, h := range f.hooks {
+		if op == OpSetUp {
+			h.AfterSetUp(ctx, ev)
+		} else {
+			h.AfterTearDown(ctx, ev)
+		}
+		if err != nil {
+			h.OnError(ctx, ev)
+		}
+	}
+	return err
+}
+
+func fixtureType(fixture Fixture) string {
+	t := reflect.TypeOf(fixture)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return fmt.Sprint(t)
+}
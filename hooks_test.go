@@ -0,0 +1,53 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	NopHook
+	events []string
+}
+
+func (h *recordingHook) BeforeSetUp(ctx context.Context, ev Event) {
+	h.events = append(h.events, "before setup "+ev.Name+" "+ev.Type)
+}
+
+func (h *recordingHook) AfterSetUp(ctx context.Context, ev Event) {
+	h.events = append(h.events, "after setup "+ev.Name)
+}
+
+func (h *recordingHook) AfterTearDown(ctx context.Context, ev Event) {
+	h.events = append(h.events, "after teardown "+ev.Name)
+}
+
+func (h *recordingHook) OnError(ctx context.Context, ev Event) {
+	h.events = append(h.events, ev.Op+" error "+ev.Name+": "+ev.Err.Error())
+}
+
+func TestFixturesHook(t *testing.T) {
+	ctx := context.Background()
+	hook := &recordingHook{}
+	fixtures := NewFixtures(FixturesHook(hook))
+
+	require.NoError(t, fixtures.AddByName(ctx, "dummy", &DummyFixture{}))
+	var running, peak int32
+	err := fixtures.AddByName(ctx, "slow", &slowFixture{err: errors.New("boom"), running: &running, peak: &peak})
+	require.Error(t, err)
+	require.NoError(t, fixtures.TearDown(ctx))
+
+	assert.Equal(t, []string{
+		"before setup dummy fixtures.DummyFixture",
+		"after setup dummy",
+		"before setup slow fixtures.slowFixture",
+		"after setup slow",
+		"setup error slow: boom",
+		"after teardown slow",
+		"after teardown dummy",
+	}, hook.events)
+}
@@ -0,0 +1,247 @@
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ory/dockertest/v3/docker"
+	"go.uber.org/zap"
+)
+
+const (
+	DEFAULT_IMAGE_REPO = "go-fixtures"
+
+	// LabelImageHash is the content hash of the build context an image was built from.
+	LabelImageHash = "go-fixtures.image-hash"
+)
+
+// imageCount numbers the images set up by this process, so that each fixture's tag is its own.
+var imageCount uint64
+
+type ImageOpt func(*Image)
+
+// NewImage returns a fixture which builds an image from a local Dockerfile. Images are cached by the content of
+// their build context, but each fixture removes its tag on teardown and the image goes with the last tag, so
+// unless ImageKeep is set the cache only helps fixtures within one process.
+func NewImage(d *Docker, opts ...ImageOpt) *Image {
+	f := &Image{
+		docker:     d,
+		dockerfile: "Dockerfile",
+		buildArgs:  map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Directory sent to docker as the build context. Relative paths which don't exist are resolved with FindPath.
+func ImageContext(dir string) ImageOpt {
+	return func(f *Image) {
+		f.contextDir = dir
+	}
+}
+
+// Path of the Dockerfile, relative to the build context. Defaults to "Dockerfile".
+func ImageDockerfile(dockerfile string) ImageOpt {
+	return func(f *Image) {
+		f.dockerfile = dockerfile
+	}
+}
+
+func ImageBuildArgs(args map[string]string) ImageOpt {
+	return func(f *Image) {
+		for k, v := range args {
+			f.buildArgs[k] = v
+		}
+	}
+}
+
+// Build this stage of a multi-stage Dockerfile.
+func ImageTarget(target string) ImageOpt {
+	return func(f *Image) {
+		f.target = target
+	}
+}
+
+// Defaults to "go-fixtures".
+func ImageRepo(repo string) ImageOpt {
+	return func(f *Image) {
+		f.repo = repo
+	}
+}
+
+// Keep the image after teardown, so later test runs can reuse it without rebuilding. Kept images must be removed by
+// hand.
+func ImageKeep() ImageOpt {
+	return func(f *Image) {
+		f.keep = true
+	}
+}
+
+func ImageLogger(logger *zap.Logger) ImageOpt {
+	return func(f *Image) {
+		f.log = logger
+	}
+}
+
+type Image struct {
+	BaseFixture
+	log        *zap.Logger
+	docker     *Docker
+	contextDir string
+	dockerfile string
+	buildArgs  map[string]string
+	target     string
+	repo       string
+	tag        string
+	hash       string
+	keep       bool
+}
+
+func (f *Image) Repo() string {
+	return f.repo
+}
+
+func (f *Image) Tag() string {
+	return f.tag
+}
+
+// Name returns "repo:tag".
+func (f *Image) Name() string {
+	return f.repo + ":" + f.tag
+}
+
+// Hash returns the content hash of the build context and options.
+func (f *Image) Hash() string {
+	return f.hash
+}
+
+// Dependencies reports the Docker fixture this image is built on.
+func (f *Image) Dependencies() []Fixture {
+	if f.docker == nil {
+		return nil
+	}
+	return []Fixture{f.docker}
+}
+
+// SetUp tags an existing image built from identical content, or builds one if none exists.
+func (f *Image) SetUp(ctx context.Context) error {
+	if f.log == nil {
+		f.log = logger()
+	}
+	if f.repo == "" {
+		f.repo = DEFAULT_IMAGE_REPO
+	}
+	dir := f.contextDir
+	if _, err := os.Stat(dir); err != nil {
+		if dir = FindPath(f.contextDir); dir == "" {
+			return fmt.Errorf("could not resolve path: %v", f.contextDir)
+		}
+	}
+
+	var err error
+	if f.hash, err = f.contentHash(dir); err != nil {
+		return fmt.Errorf("failed to hash build context: %w", err)
+	}
+	f.tag = fmt.Sprintf("%v-%v-%v", f.hash[:12], sessionID[:8], atomic.AddUint64(&imageCount, 1))
+
+	client := f.docker.Pool().Client
+	cached, err := client.ListImages(docker.ListImagesOptions{
+		Filters: map[string][]string{"label": {LabelImageHash + "=" + f.hash}},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing docker images: %w", err)
+	}
+	if len(cached) > 0 {
+		f.log.Debug("reusing image", zap.String("image", f.Name()), zap.String("id", cached[0].ID))
+		return client.TagImage(cached[0].ID, docker.TagImageOptions{Repo: f.repo, Tag: f.tag, Context: ctx})
+	}
+
+	// Images are shared by every session which tags them, so they don't carry session labels for Reap to find.
+	labels := map[string]string{LabelImageHash: f.hash}
+	buildArgs := []docker.BuildArg{}
+	for _, k := range sortedKeys(f.buildArgs) {
+		buildArgs = append(buildArgs, docker.BuildArg{Name: k, Value: f.buildArgs[k]})
+	}
+	f.log.Debug("building image", zap.String("image", f.Name()), zap.String("context", dir))
+	if err := client.BuildImage(docker.BuildImageOptions{
+		Name:           f.Name(),
+		Dockerfile:     f.dockerfile,
+		ContextDir:     dir,
+		BuildArgs:      buildArgs,
+		Target:         f.target,
+		Labels:         labels,
+		RmTmpContainer: true,
+		OutputStream:   io.Discard,
+		Context:        ctx,
+	}); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	return nil
+}
+
+// TearDown removes this fixture's tag. The image itself is only deleted once no other tags refer to it.
+func (f *Image) TearDown(context.Context) error {
+	if f.keep || f.tag == "" {
+		return nil
+	}
+	// Containers run from the image may still be being purged, and docker refuses to remove an image in use.
+	f.docker.Wait()
+	return f.docker.Pool().Client.RemoveImage(f.Name())
+}
+
+// contentHash hashes every file in the build context along with the build options.
+func (f *Image) contentHash(dir string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "dockerfile=%v\ntarget=%v\n", f.dockerfile, f.target)
+	for _, k := range sortedKeys(f.buildArgs) {
+		fmt.Fprintf(h, "arg %v=%v\n", k, f.buildArgs[k])
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%v %v\n", filepath.ToSlash(rel), info.Mode())
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(h, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
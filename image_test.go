@@ -0,0 +1,59 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageContentHash(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\n"), 0o644))
+
+	img := NewImage(nil, ImageContext(dir))
+	h1, err := img.contentHash(dir)
+	require.NoError(t, err)
+	h2, err := img.contentHash(dir)
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("hello"), 0o644))
+	h3, err := img.contentHash(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+
+	withArgs := NewImage(nil, ImageContext(dir), ImageBuildArgs(map[string]string{"VERSION": "1"}))
+	h4, err := withArgs.contentHash(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, h3, h4)
+}
+
+func TestImage(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	f := New(t)
+	d := NewDocker()
+	img := NewImage(d, ImageContext("test"))
+	c := NewContainer(d,
+		ContainerImage(img),
+		ContainerWaitFor(ForHealthCheck(), ForHTTP("8000", "/")),
+		ContainerReadyTimeout(time.Minute),
+	)
+	f.AddT(t, d, img, c)
+	assert.NotEmpty(t, img.Hash())
+
+	// Unchanged contexts are not rebuilt.
+	img2 := NewImage(d, ImageContext("test"))
+	f.AddT(t, img2)
+	assert.Equal(t, img.Hash(), img2.Hash())
+	assert.NotEqual(t, img.Name(), img2.Name())
+	i1, err := d.Pool().Client.InspectImage(img.Name())
+	require.NoError(t, err)
+	i2, err := d.Pool().Client.InspectImage(img2.Name())
+	require.NoError(t, err)
+	assert.Equal(t, i1.ID, i2.ID)
+}
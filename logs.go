@@ -0,0 +1,145 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+const DEFAULT_LOG_TAIL = 100
+
+// LogTailer is implemented by fixtures which capture the output of a process, so that it can be reported when
+// setup or a test fails.
+type LogTailer interface {
+	// LogTail returns up to the last n lines of output, or everything captured if n <= 0.
+	LogTail(n int) []string
+}
+
+// logRing keeps the most recent lines written to it.
+type logRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLogRing(size int) *logRing {
+	if size < 0 {
+		size = 0
+	}
+	return &logRing{lines: make([]string, size)}
+}
+
+func (r *logRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.lines) == 0 {
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *logRing) tail(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lines []string
+	if r.full {
+		lines = append(lines, r.lines[r.next:]...)
+	}
+	lines = append(lines, r.lines[:r.next]...)
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// lineWriter calls fn with each complete line written to it.
+type lineWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	fn  func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf.Next(i + 1))
+		w.fn(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush emits any trailing partial line.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.fn(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// streamLogs follows the container's stdout and stderr until it exits or ctx is done, keeping the most recent
+// lines in ring and copying each line to out with a prefix.
+func streamLogs(ctx context.Context, pool *dockertest.Pool, containerID string, ring *logRing, out io.Writer, prefix string) {
+	w := &lineWriter{fn: func(line string) {
+		ring.add(line)
+		if out != nil {
+			io.WriteString(out, prefix+line+"\n")
+		}
+	}}
+	go func() {
+		defer w.Flush()
+		pool.Client.Logs(docker.LogsOptions{
+			Context:      ctx,
+			Container:    containerID,
+			OutputStream: w,
+			ErrorStream:  w,
+			Follow:       true,
+			Stdout:       true,
+			Stderr:       true,
+		})
+	}()
+}
+
+// logTail formats the output captured by the entry's fixture for reporting. Output is reported at most once per
+// setup, and "" is returned if there is nothing to report. It must be called with e.mu held.
+func (e *entry) logTail() string {
+	tailer, ok := e.fixture.(LogTailer)
+	if !ok || e.logsReported {
+		return ""
+	}
+	lines := tailer.LogTail(0)
+	if len(lines) == 0 {
+		return ""
+	}
+	e.logsReported = true
+	return fmt.Sprintf("output of fixture '%v' (%v):\n\t%v", e.name, fixtureType(e.fixture), strings.Join(lines, "\n\t"))
+}
+
+// reportLogs writes the output captured by each of the entries through logf.
+func reportLogs(logf func(format string, args ...interface{}), entries []*entry) {
+	for _, e := range entries {
+		e.mu.Lock()
+		tail := e.logTail()
+		e.mu.Unlock()
+		if tail != "" {
+			logf("%v", tail)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogRing(t *testing.T) {
+	r := newLogRing(3)
+	assert.Empty(t, r.tail(0))
+	r.add("a")
+	r.add("b")
+	assert.Equal(t, []string{"a", "b"}, r.tail(0))
+	r.add("c")
+	r.add("d")
+	assert.Equal(t, []string{"b", "c", "d"}, r.tail(0))
+	assert.Equal(t, []string{"c", "d"}, r.tail(2))
+	assert.Equal(t, []string{"b", "c", "d"}, r.tail(10))
+
+	empty := newLogRing(-1)
+	empty.add("a")
+	assert.Empty(t, empty.tail(0))
+}
+
+func TestLogLineWriter(t *testing.T) {
+	lines := []string{}
+	w := &lineWriter{fn: func(line string) { lines = append(lines, line) }}
+	w.Write([]byte("one\r\ntw"))
+	w.Write([]byte("o\nthree\nfo"))
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+	w.Flush()
+	assert.Equal(t, []string{"one", "two", "three", "fo"}, lines)
+}
+
+type tailingFixture struct {
+	BaseFixture
+	err   error
+	lines []string
+}
+
+func (tf *tailingFixture) SetUp(context.Context) error {
+	return tf.err
+}
+
+func (tf *tailingFixture) TearDown(context.Context) error {
+	return nil
+}
+
+func (tf *tailingFixture) LogTail(n int) []string {
+	return tf.lines
+}
+
+func TestLogTailOnSetUpFailure(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	f := NewFixtures(FixturesLogger(zap.New(core)))
+	fix := &tailingFixture{err: errors.New("boom"), lines: []string{"starting", "crashed"}}
+	require.Error(t, f.AddByName(context.Background(), "server", fix))
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "output of fixture 'server' (fixtures.tailingFixture):\n\tstarting\n\tcrashed", entries[0].Message)
+
+	// Output is only reported once per setup.
+	var buf bytes.Buffer
+	f.mu.Lock()
+	all := f.tearDownOrder(nil)
+	f.mu.Unlock()
+	reportLogs(func(format string, args ...interface{}) { buf.WriteString("reported") }, all)
+	assert.Empty(t, buf.String())
+}
+
+// lateContainerFixture starts capturing a container's output only after its setup has timed out.
+type lateContainerFixture struct {
+	BaseFixture
+	container *Container
+	started   chan struct{}
+}
+
+func (lf *lateContainerFixture) SetUp(ctx context.Context) error {
+	<-ctx.Done()
+	logs := newLogRing(10)
+	logs.add("too late")
+	lf.container.setLogs(logs)
+	close(lf.started)
+	return ctx.Err()
+}
+
+func (lf *lateContainerFixture) TearDown(context.Context) error {
+	return nil
+}
+
+func (lf *lateContainerFixture) LogTail(n int) []string {
+	return lf.container.LogTail(n)
+}
+
+func TestLogTailOnSetUpTimeout(t *testing.T) {
+	f := NewFixtures(FixturesLogger(zap.NewNop()))
+	fix := &lateContainerFixture{container: NewContainer(nil), started: make(chan struct{})}
+	err := f.AddByName(context.Background(), "late", fix, FixtureSetUpTimeout(10*time.Millisecond))
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	<-fix.started
+	assert.Equal(t, []string{"too late"}, fix.LogTail(0))
+}
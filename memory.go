@@ -1,6 +1,11 @@
 package fixtures
 
 import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/tklauser/go-sysconf"
 )
 
@@ -15,3 +20,51 @@ func mustSysconf(name int) int64 {
 func MemoryMB() int64 {
 	return int64(mustSysconf(sysconf.SC_PHYS_PAGES)*mustSysconf(sysconf.SC_PAGE_SIZE)) / 1e6
 }
+
+// CgroupMemoryMB returns the memory limit of the cgroup this process runs in, such as a CI job's container,
+// or 0 if it is unlimited.
+func CgroupMemoryMB() int64 {
+	self, _ := os.ReadFile("/proc/self/cgroup")
+	limit := cgroupMemoryLimit("/sys/fs/cgroup", string(self))
+	if limit <= 0 || limit/1e6 >= MemoryMB() {
+		return 0
+	}
+	return limit / 1e6
+}
+
+// AvailableMemoryMB returns the memory this process may use: the cgroup limit if there is one, otherwise MemoryMB.
+func AvailableMemoryMB() int64 {
+	if mb := CgroupMemoryMB(); mb > 0 {
+		return mb
+	}
+	return MemoryMB()
+}
+
+// cgroupMemoryLimit reads the memory limit in bytes from a cgroup v2 or v1 hierarchy mounted at root, given the
+// contents of /proc/self/cgroup. It returns 0 if no limit is found.
+func cgroupMemoryLimit(root string, self string) int64 {
+	paths := []string{}
+	for _, line := range strings.Split(self, "\n") {
+		// cgroup v2 entries look like "0::/path".
+		if path := strings.TrimPrefix(line, "0::"); path != line {
+			paths = append(paths, filepath.Join(root, path, "memory.max"))
+		}
+	}
+	paths = append(paths,
+		filepath.Join(root, "memory.max"),
+		filepath.Join(root, "memory", "memory.limit_in_bytes"),
+	)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// "max" means unlimited under cgroup v2.
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
@@ -1,10 +1,48 @@
 package fixtures
 
 import (
-	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMemory(t *testing.T) {
-	fmt.Println("memory MB", MemoryMB())
+	t.Logf("memory: %vMB, cgroup limit: %vMB", MemoryMB(), CgroupMemoryMB())
+	assert.Greater(t, MemoryMB(), int64(0))
+	assert.LessOrEqual(t, AvailableMemoryMB(), MemoryMB())
+	if limit := CgroupMemoryMB(); limit > 0 {
+		assert.LessOrEqual(t, AvailableMemoryMB(), limit)
+	}
+}
+
+func TestCgroupMemoryLimit(t *testing.T) {
+	write := func(t *testing.T, path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("V2", func(t *testing.T) {
+		root := t.TempDir()
+		write(t, filepath.Join(root, "ci.slice", "job.scope", "memory.max"), "2000000000\n")
+		assert.Equal(t, int64(2e9), cgroupMemoryLimit(root, "0::/ci.slice/job.scope\n"))
+	})
+
+	t.Run("V2Unlimited", func(t *testing.T) {
+		root := t.TempDir()
+		write(t, filepath.Join(root, "memory.max"), "max\n")
+		assert.Equal(t, int64(0), cgroupMemoryLimit(root, "0::/\n"))
+	})
+
+	t.Run("V1", func(t *testing.T) {
+		root := t.TempDir()
+		write(t, filepath.Join(root, "memory", "memory.limit_in_bytes"), "536870912\n")
+		assert.Equal(t, int64(536870912), cgroupMemoryLimit(root, "4:memory:/docker/0123abcd\n"))
+	})
+
+	t.Run("None", func(t *testing.T) {
+		assert.Equal(t, int64(0), cgroupMemoryLimit(t.TempDir(), ""))
+	})
 }
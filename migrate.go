@@ -0,0 +1,253 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const DEFAULT_MIGRATIONS_TABLE = "schema_migrations"
+
+type MigrateOpt func(*migrateConfig)
+
+type migrateConfig struct {
+	target *int64
+	table  string
+}
+
+// Migrate up or down to this version. Defaults to the latest version; 0 reverts every migration.
+func MigrateTo(version int64) MigrateOpt {
+	return func(c *migrateConfig) {
+		c.target = &version
+	}
+}
+
+// Record applied versions in this table. Defaults to "schema_migrations".
+func MigrateTable(table string) MigrateOpt {
+	return func(c *migrateConfig) {
+		c.table = table
+	}
+}
+
+// Migration is a numbered schema change, read from "<version>_<name>.up.sql" and "<version>_<name>.down.sql".
+// A file named "<version>_<name>.sql" is treated as an up migration.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationError reports the migration which failed, and the line of the statement which failed if postgres
+// reported one.
+type MigrationError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *MigrationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("migration %v failed at line %v: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("migration %v failed: %v", e.File, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+// ReadMigrations reads the migrations in the root of source, ordered by version. Use os.DirFS to read a directory
+// and fs.Sub to read a directory of an embed.FS.
+func ReadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %v", entry.Name())
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migrations %v_%v and %v share a version", version, migration.Name, entry.Name())
+		}
+		if m[3] == ".down" {
+			migration.Down = entry.Name()
+		} else if migration.Up != "" {
+			return nil, fmt.Errorf("migration %v has more than one up migration", entry.Name())
+		} else {
+			migration.Up = entry.Name()
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies the migrations in source to the primary database. See Migrate.
+func (f *Postgres) Migrate(ctx context.Context, source fs.FS, opts ...MigrateOpt) error {
+	db, err := f.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return Migrate(ctx, db, source, opts...)
+}
+
+// Migrate applies the migrations in source which haven't been applied, or reverts those above the target version.
+// Each migration runs in its own transaction along with the update to the table of applied versions, so processes
+// migrating the same database at once apply each migration once. Migrations are run as scripts, like RunScript,
+// except that \connect isn't supported and BEGIN and COMMIT become savepoints.
+func Migrate(ctx context.Context, db DB, source fs.FS, opts ...MigrateOpt) error {
+	cfg := &migrateConfig{table: DEFAULT_MIGRATIONS_TABLE}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	migrations, err := ReadMigrations(source)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	var target int64
+	if cfg.target != nil {
+		target = *cfg.target
+	} else if len(migrations) > 0 {
+		target = migrations[len(migrations)-1].Version
+	}
+
+	table := pgx.Identifier{cfg.table}.Sanitize()
+	if err := db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockMigrations(ctx, tx, table); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+table+" (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())")
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied, err := appliedVersions(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version > target || applied[m.Version] || m.Up == "" {
+			continue
+		}
+		if err := runMigration(ctx, db, source, table, m, true); err != nil {
+			return err
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target || !applied[m.Version] {
+			continue
+		}
+		if m.Down == "" {
+			return fmt.Errorf("migration %v_%v can't be reverted: it has no down migration", m.Version, m.Name)
+		}
+		if err := runMigration(ctx, db, source, table, m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db DB, table string) (map[int64]bool, error) {
+	rows, err := db.Query(ctx, "SELECT version FROM "+table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// runMigration runs a migration file as a script and records it in one transaction. See RunScript. Transaction
+// control in the file becomes savepoints, as in a TestTx, so it can't commit the migration part-way through.
+// Concurrent migrations of the same table are serialized by an advisory lock, and a migration which another
+// process applied or reverted first is skipped.
+func runMigration(ctx context.Context, db DB, source fs.FS, table string, m Migration, up bool) error {
+	file, record := m.Up, "INSERT INTO "+table+" (version, name) VALUES ($1, $2)"
+	args := []interface{}{m.Version, m.Name}
+	if !up {
+		file, record = m.Down, "DELETE FROM "+table+" WHERE version = $1"
+		args = args[:1]
+	}
+	return db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockMigrations(ctx, tx, table); err != nil {
+			return err
+		}
+		var applied bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT FROM "+table+" WHERE version = $1)", m.Version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		if applied == up {
+			return nil
+		}
+		r := &scriptRunner{fsys: source, vars: map[string]string{}, db: &TestTx{tx: tx}, conn: tx.Conn()}
+		if err := r.run(ctx, file, 0); err != nil {
+			var scriptErr *ScriptError
+			if errors.As(err, &scriptErr) {
+				return &MigrationError{File: scriptErr.File, Line: scriptErr.Line, Err: scriptErr.Err}
+			}
+			return &MigrationError{File: file, Err: err}
+		}
+		if _, err := tx.Exec(ctx, record, args...); err != nil {
+			return fmt.Errorf("failed to record migration %v: %w", file, err)
+		}
+		return nil
+	})
+}
+
+// lockMigrations holds an advisory lock on the table of applied versions until tx ends.
+func lockMigrations(ctx context.Context, tx pgx.Tx, table string) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "go-fixtures migrate "+table); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	return nil
+}
+
+// errorLine returns the line of sql at which postgres reported err, or 0 if it didn't report a position.
+func errorLine(sql string, err error) int {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Position <= 0 {
+		return 0
+	}
+	// Position counts characters from 1.
+	runes := []rune(sql)
+	pos := int(pgErr.Position) - 1
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+	return strings.Count(string(runes[:pos]), "\n") + 1
+}
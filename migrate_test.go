@@ -0,0 +1,135 @@
+package fixtures
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/migrate
+var testMigrations embed.FS
+
+func TestReadMigrations(t *testing.T) {
+	migrations, err := ReadMigrations(fstest.MapFS{
+		"10_index.sql":        {Data: []byte("CREATE INDEX ...")},
+		"2_person.up.sql":     {Data: []byte("CREATE TABLE person ...")},
+		"2_person.down.sql":   {Data: []byte("DROP TABLE person")},
+		"1_address.up.sql":    {Data: []byte("CREATE TABLE address ...")},
+		"README.md":           {Data: []byte("# migrations")},
+		"seed/1_seed.up.sql":  {Data: []byte("INSERT ...")},
+		"1_address.down.sql":  {Data: []byte("DROP TABLE address")},
+		"3_backfill.down.sql": {Data: []byte("DELETE ...")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "address", Up: "1_address.up.sql", Down: "1_address.down.sql"},
+		{Version: 2, Name: "person", Up: "2_person.up.sql", Down: "2_person.down.sql"},
+		{Version: 3, Name: "backfill", Down: "3_backfill.down.sql"},
+		{Version: 10, Name: "index", Up: "10_index.sql"},
+	}, migrations)
+
+	_, err = ReadMigrations(fstest.MapFS{
+		"1_address.up.sql": {Data: []byte("")},
+		"1_person.up.sql":  {Data: []byte("")},
+	})
+	assert.Error(t, err)
+
+	sub, err := fs.Sub(testMigrations, "testdata/migrate")
+	require.NoError(t, err)
+	migrations, err = ReadMigrations(sub)
+	require.NoError(t, err)
+	assert.Len(t, migrations, 2)
+}
+
+func TestMigrationErrorLine(t *testing.T) {
+	sql := "CREATE TABLE a (id int);\nCREATE TABLE b (\n    id int,\n    oops\n);"
+	err := &pgconn.PgError{Severity: "ERROR", Message: "syntax error", Code: "42601", Position: 59}
+	assert.Equal(t, 4, errorLine(sql, err))
+	assert.Equal(t, 0, errorLine(sql, &pgconn.PgError{}))
+	assert.Equal(t, 0, errorLine(sql, errors.New("connection reset")))
+
+	merr := &MigrationError{File: "2_b.up.sql", Line: 4, Err: err}
+	assert.EqualError(t, merr, "migration 2_b.up.sql failed at line 4: ERROR: syntax error (SQLSTATE 42601)")
+}
+
+func TestPostgresMigrate(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+
+	source, err := fs.Sub(testMigrations, "testdata/migrate")
+	require.NoError(t, err)
+
+	require.NoError(t, pg.Migrate(ctx, source))
+	tables, err := pg.Tables(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"address", "person", DEFAULT_MIGRATIONS_TABLE}, tables)
+
+	// Applied migrations are skipped.
+	require.NoError(t, pg.Migrate(ctx, source))
+
+	require.NoError(t, pg.Migrate(ctx, source, MigrateTo(1)))
+	exists, err := pg.TableExists(ctx, "", "public", "person")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, pg.Migrate(ctx, source, MigrateTo(0)))
+	exists, err = pg.TableExists(ctx, "", "public", "address")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = pg.Migrate(ctx, fstest.MapFS{"1_broken.sql": {Data: []byte("SELECT 1;\nSELEC 2;")}}, MigrateTable("broken_migrations"))
+	var merr *MigrationError
+	require.ErrorAs(t, err, &merr)
+	assert.Equal(t, "1_broken.sql", merr.File)
+	assert.Equal(t, 2, merr.Line)
+
+	// Migrations are scripts, so they can use meta-commands and inline COPY data.
+	scripts := fstest.MapFS{
+		"1_color.up.sql": {Data: []byte("\\set table color\nCREATE TABLE :\"table\" (name text);\n\\i seed/color.sql\n")},
+		"seed/color.sql": {Data: []byte("COPY color (name) FROM stdin;\nred\ngreen\n\\.\n")},
+	}
+	require.NoError(t, pg.Migrate(ctx, scripts, MigrateTable("script_migrations")))
+	db := pg.MustConnect(ctx)
+	defer db.Close()
+	var colors int
+	require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM color").Scan(&colors))
+	assert.Equal(t, 2, colors)
+
+	// Transaction control in a migration can't commit it part-way through.
+	err = pg.Migrate(ctx, fstest.MapFS{"1_shape.sql": {Data: []byte("BEGIN;\nCREATE TABLE shape (name text);\nCOMMIT;\nSELEC 1;")}}, MigrateTable("shape_migrations"))
+	require.ErrorAs(t, err, &merr)
+	exists, err = pg.TableExists(ctx, "", "public", "shape")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Concurrent migrations of the same database apply each migration once.
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = pg.Migrate(ctx, source, MigrateTable("concurrent_migrations"))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	var applied int
+	require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM concurrent_migrations").Scan(&applied))
+	assert.Equal(t, 2, applied)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charlieparkes/go-structs"
@@ -85,6 +86,30 @@ func PostgresMounts(mounts []string) PostgresOpt {
 	}
 }
 
+// Reuse a postgres container left running by an earlier test run with the same configuration. Data persists
+// between runs. See ContainerReuse.
+func PostgresReuse() PostgresOpt {
+	return func(f *Postgres) {
+		f.reuse = true
+		f.containerOpts = append(f.containerOpts, ContainerReuse())
+	}
+}
+
+// Limit the container's memory. Postgres is tuned for this limit, unlike a limit passed with
+// PostgresContainerOpts. See ContainerMemoryMB.
+func PostgresMemoryMB(mb int64) PostgresOpt {
+	return func(f *Postgres) {
+		f.memoryLimitMB = mb
+	}
+}
+
+// PostgresContainerOpts passes options through to the underlying Container.
+func PostgresContainerOpts(opts ...ContainerOpt) PostgresOpt {
+	return func(f *Postgres) {
+		f.containerOpts = append(f.containerOpts, opts...)
+	}
+}
+
 func PostgresLogger(logger *zap.Logger) PostgresOpt {
 	return func(f *Postgres) {
 		f.log = logger
@@ -93,16 +118,24 @@ func PostgresLogger(logger *zap.Logger) PostgresOpt {
 
 type Postgres struct {
 	BaseFixture
-	log          *zap.Logger
-	docker       *Docker
-	settings     *ConnectionSettings
-	resource     *dockertest.Resource
-	repo         string
-	version      string
-	expireAfter  uint
-	timeoutAfter uint
-	skipTearDown bool
-	mounts       []string
+	log           *zap.Logger
+	docker        *Docker
+	container     *Container
+	containerOpts []ContainerOpt
+	memoryLimitMB int64
+	settings      *ConnectionSettings
+	resource      *dockertest.Resource
+	repo          string
+	version       string
+	expireAfter   uint
+	timeoutAfter  uint
+	skipTearDown  bool
+	reuse         bool
+	mounts        []string
+	template      *Template
+	templateMu    sync.Mutex
+	// containerMu guards container, which LogTail may read while SetUp is still running after timing out.
+	containerMu sync.Mutex
 }
 
 func (f *Postgres) Settings() *ConnectionSettings {
@@ -113,6 +146,14 @@ func (f *Postgres) ConnConfig() (*pgxpool.Config, error) {
 	return pgxpool.ParseConfig(f.settings.String())
 }
 
+// Dependencies reports the Docker fixture this container runs on.
+func (f *Postgres) Dependencies() []Fixture {
+	if f.docker == nil {
+		return nil
+	}
+	return []Fixture{f.docker}
+}
+
 func (f *Postgres) SetUp(ctx context.Context) error {
 	if f.log == nil {
 		f.log = logger()
@@ -130,43 +171,47 @@ func (f *Postgres) SetUp(ctx context.Context) error {
 			Database:   f.docker.NamePrefix(),
 			DisableSSL: true,
 		}
+		if f.reuse || f.docker.Reuse() {
+			// The password is part of the container's configuration, so it must be stable to be reused.
+			f.settings.Password = "postgres"
+		}
 	}
-	networks := make([]*dockertest.Network, 0)
-	if f.docker.Network() != nil {
-		networks = append(networks, f.docker.Network())
-	}
-	opts := dockertest.RunOptions{
-		Repository: f.repo,
-		Tag:        f.version,
-		Env: []string{
-			"POSTGRES_USER=" + f.settings.User,
-			"POSTGRES_PASSWORD=" + f.settings.Password,
-			"POSTGRES_DB=" + f.settings.Database,
-		},
-		Networks: networks,
-		Cmd: []string{
+	memoryMB := f.memoryMB()
+	opts := []ContainerOpt{
+		ContainerRepo(f.repo),
+		ContainerTag(f.version),
+		ContainerEnv(
+			"POSTGRES_USER="+f.settings.User,
+			"POSTGRES_PASSWORD="+f.settings.Password,
+			"POSTGRES_DB="+f.settings.Database,
+		),
+		ContainerCmd(
 			// https://www.postgresql.org/docs/current/non-durability.html
 			"-c", "fsync=off",
 			"-c", "synchronous_commit=off",
 			"-c", "full_page_writes=off",
 			"-c", "random_page_cost=1.1",
-			"-c", fmt.Sprintf("shared_buffers=%vMB", MemoryMB()/8),
-			"-c", fmt.Sprintf("work_mem=%vMB", MemoryMB()/8),
-		},
-		Mounts: f.mounts,
-	}
-	var err error
-	f.resource, err = f.docker.Pool().RunWithOptions(&opts)
+			"-c", fmt.Sprintf("shared_buffers=%vMB", memoryMB/8),
+			"-c", fmt.Sprintf("work_mem=%vMB", memoryMB/8),
+		),
+		ContainerMounts(f.mounts...),
+		ContainerExpireAfter(f.expireAfter),
+		ContainerLogger(f.log),
+	}
+	if f.memoryLimitMB > 0 {
+		opts = append(opts, ContainerMemoryMB(f.memoryLimitMB))
+	}
+	container := NewContainer(f.docker, append(opts, f.containerOpts...)...)
+	f.containerMu.Lock()
+	f.container = container
+	f.containerMu.Unlock()
+	err := f.container.SetUp(ctx)
+	f.resource = f.container.Resource()
 	if err != nil {
 		return err
 	}
 
-	f.settings.Host = ContainerAddress(f.resource, f.docker.Network())
-
-	if f.expireAfter == 0 {
-		f.expireAfter = 600
-	}
-	f.resource.Expire(f.expireAfter)
+	f.settings.Host = f.container.Host()
 
 	if f.timeoutAfter == 0 {
 		f.timeoutAfter = 30
@@ -177,12 +222,47 @@ func (f *Postgres) SetUp(ctx context.Context) error {
 	return nil
 }
 
+// memoryMB returns the memory to tune postgres for: the container's limit if it has one, otherwise the memory
+// available to the tests, which may itself be limited by a cgroup.
+func (f *Postgres) memoryMB() int64 {
+	if f.memoryLimitMB > 0 {
+		return f.memoryLimitMB
+	}
+	return AvailableMemoryMB()
+}
+
 func (f *Postgres) TearDown(ctx context.Context) error {
-	if f.skipTearDown {
+	var errs Errors
+	f.templateMu.Lock()
+	if f.template != nil {
+		if err := f.template.close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		f.template = nil
+	}
+	f.templateMu.Unlock()
+	if !f.skipTearDown && f.container != nil {
+		if err := f.container.TearDown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// Container returns the container postgres is running in.
+func (f *Postgres) Container() *Container {
+	return f.container
+}
+
+// LogTail returns up to the last n lines of server output, or everything kept if n <= 0. See ContainerLogTail.
+func (f *Postgres) LogTail(n int) []string {
+	f.containerMu.Lock()
+	container := f.container
+	f.containerMu.Unlock()
+	if container == nil {
 		return nil
 	}
-	f.docker.Purge(f.resource)
-	return nil
+	return container.LogTail(n)
 }
 
 type PostgresConnConfig struct {
@@ -208,6 +288,8 @@ func PostgresConnDatabase(database string) PostgresConnOpt {
 	}
 }
 
+// PostgresConnCreateCopy connects to a new copy of the database. The copy is never dropped; in tests, prefer
+// Postgres.IsolatedDB.
 func PostgresConnCreateCopy() PostgresConnOpt {
 	return func(f *PostgresConnConfig) {
 		f.createCopy = true
@@ -260,6 +342,28 @@ func (f *Postgres) HostName() string {
 	return HostName(f.resource)
 }
 
+// Exec runs a command inside the postgres container with PGUSER, PGPASSWORD and PGDATABASE set, so psql, pg_dump,
+// createdb and friends connect to the primary database. A non-zero exit code is returned as an *ExecError.
+func (f *Postgres) Exec(ctx context.Context, cmd []string, opts ExecOptions) (ExecResult, error) {
+	opts.Env = append([]string{
+		"PGUSER=" + f.settings.User,
+		"PGPASSWORD=" + f.settings.Password,
+		"PGDATABASE=" + f.settings.Database,
+	}, opts.Env...)
+	res, err := f.container.Exec(ctx, cmd, opts)
+	if err != nil {
+		return res, err
+	}
+	if res.ExitCode != 0 {
+		f.log.Debug("exec failed", zap.Int("status", res.ExitCode), zap.String("container", f.HostName()), zap.String("cmd", strings.Join(cmd, " ")))
+		return res, &ExecError{Cmd: cmd, ExitCode: res.ExitCode, Stderr: res.Stderr}
+	}
+	return res, nil
+}
+
+// Psql runs cmd in a sidecar container with psql installed, connected over the docker network.
+//
+// Deprecated: Use Exec, which runs commands inside the postgres container.
 func (f *Postgres) Psql(ctx context.Context, cmd []string, mounts []string, quiet bool) (int, error) {
 	// We're going to connect over the docker network
 	settings := f.settings.Copy()
@@ -282,7 +386,7 @@ func (f *Postgres) Psql(ctx context.Context, cmd []string, mounts []string, quie
 		Cmd: cmd,
 	}
 	// f.log.Debug("psql setup", zap.Any("environment", opts.Env))
-	resource, err := f.docker.Pool().RunWithOptions(&opts)
+	resource, err := f.docker.RunWithOptions(&opts)
 	if err != nil {
 		return 0, err
 	}
@@ -302,7 +406,7 @@ func (f *Postgres) Psql(ctx context.Context, cmd []string, mounts []string, quie
 }
 
 func (f *Postgres) PingPsql(ctx context.Context) error {
-	_, err := f.Psql(ctx, []string{"psql", "-c", ";"}, []string{}, false)
+	_, err := f.Exec(ctx, []string{"psql", "-c", ";"}, ExecOptions{})
 	return err
 }
 
@@ -319,8 +423,8 @@ func (f *Postgres) CreateDatabase(ctx context.Context, name string) error {
 	if name == "" {
 		return errors.New("must provide a database name")
 	}
-	exitCode, err := f.Psql(ctx, []string{"createdb", "--template=template0", name}, []string{}, false)
-	f.log.Debug("create database", zap.Int("status", exitCode), zap.String("database", name), zap.String("container", f.HostName()))
+	res, err := f.Exec(ctx, []string{"createdb", "--template=template0", name}, ExecOptions{})
+	f.log.Debug("create database", zap.Int("status", res.ExitCode), zap.String("database", name), zap.String("container", f.HostName()))
 	return err
 }
 
@@ -330,8 +434,8 @@ func (f *Postgres) CopyDatabase(ctx context.Context, source string, target strin
 	if source == "" {
 		source = f.settings.Database
 	}
-	exitCode, err := f.Psql(ctx, []string{"createdb", fmt.Sprintf("--template=%v", source), target}, []string{}, false)
-	f.log.Debug("copy database", zap.Int("status", exitCode), zap.String("source", source), zap.String("target", target), zap.String("container", f.HostName()))
+	res, err := f.Exec(ctx, []string{"createdb", fmt.Sprintf("--template=%v", source), target}, ExecOptions{})
+	f.log.Debug("copy database", zap.Int("status", res.ExitCode), zap.String("source", source), zap.String("target", target), zap.String("container", f.HostName()))
 	return err
 }
 
@@ -354,61 +458,55 @@ func (f *Postgres) DropDatabase(ctx context.Context, name string) error {
 		return err
 	}
 
-	exitCode, err := f.Psql(ctx, []string{"dropdb", name}, []string{}, false)
-	f.log.Debug("drop database", zap.Int("status", exitCode), zap.String("database", name), zap.String("container", f.HostName()))
+	res, err := f.Exec(ctx, []string{"dropdb", name}, ExecOptions{})
+	f.log.Debug("drop database", zap.Int("status", res.ExitCode), zap.String("database", name), zap.String("container", f.HostName()))
 	return err
 }
 
+// Dump writes the primary database to dir/filename on the host in pg_dump's custom format.
 func (f *Postgres) Dump(ctx context.Context, dir string, filename string) error {
 	path := FindPath(dir)
 	if path == "" {
 		return fmt.Errorf("could not resolve path: %v", dir)
 	}
-	exitCode, err := f.Psql(ctx, []string{"sh", "-c", fmt.Sprintf("pg_dump -Fc -Z0 %v > /tmp/%v", f.settings.Database, filename)}, []string{fmt.Sprintf("%v:/tmp", path)}, false)
-	f.log.Debug("dump database", zap.Int("status", exitCode), zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("path", path))
-	return err
+	tmp := "/tmp/" + filename
+	res, err := f.Exec(ctx, []string{"pg_dump", "-Fc", "-Z0", "--file=" + tmp, f.settings.Database}, ExecOptions{})
+	f.log.Debug("dump database", zap.Int("status", res.ExitCode), zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("path", path))
+	if err != nil {
+		return err
+	}
+	defer f.Exec(ctx, []string{"rm", "-f", tmp}, ExecOptions{})
+	return f.container.CopyFrom(ctx, tmp, path)
 }
 
+// Restore loads dir/filename from the host, as written by Dump, into the primary database.
 func (f *Postgres) Restore(ctx context.Context, dir string, filename string) error {
 	path := FindPath(dir)
 	if path == "" {
 		return fmt.Errorf("could not resolve path: %v", dir)
 	}
-	exitCode, err := f.Psql(ctx, []string{"sh", "-c", fmt.Sprintf("pg_restore --dbname=%v --verbose --single-transaction /tmp/%v", f.settings.Database, filename)}, []string{fmt.Sprintf("%v:/tmp", path)}, false)
-	f.log.Debug("restore database", zap.Int("status", exitCode), zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("path", path))
+	if err := f.container.CopyTo(ctx, filepath.Join(path, filename), "/tmp"); err != nil {
+		return err
+	}
+	return f.restore(ctx, "/tmp/"+filepath.Base(filename))
+}
+
+// restore runs pg_restore on a dump inside the container, then removes it.
+func (f *Postgres) restore(ctx context.Context, tmp string) error {
+	defer f.Exec(ctx, []string{"rm", "-f", tmp}, ExecOptions{})
+	res, err := f.Exec(ctx, []string{"pg_restore", "--dbname=" + f.settings.Database, "--verbose", "--single-transaction", tmp}, ExecOptions{})
+	f.log.Debug("restore database", zap.Int("status", res.ExitCode), zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("path", tmp))
 	return err
 }
 
-// LoadSql runs a file or directory of *.sql files against the default postgres database.
+// LoadSql runs a file or directory of *.sql files against the default postgres database. See RunScript; \i
+// resolves paths relative to the directory of the file.
 func (f *Postgres) LoadSql(ctx context.Context, path string) error {
-	load := func(p string) error {
-		dir, err := filepath.Abs(filepath.Dir(p))
-		if err != nil {
-			return err
-		}
-		name := filepath.Base(p)
-		exitCode, err := f.Psql(ctx, []string{"psql", fmt.Sprintf("--file=/tmp/%v", name)}, []string{fmt.Sprintf("%v:/tmp", dir)}, false)
-		f.log.Debug("load sql", zap.Int("status", exitCode), zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("name", name))
-		if err != nil {
-			return fmt.Errorf("failed to run psql (load sql): %w", err)
-		}
-		return nil
-	}
-
 	if info, err := os.Stat(path); err == nil {
 		if info.IsDir() {
-			files, err := filepath.Glob(filepath.Join(path, "*.sql"))
-			if err != nil {
-				return err
-			}
-			for _, path := range files {
-				if err := load(path); err != nil {
-					return err
-				}
-			}
-		} else {
-			return load(path)
+			return f.LoadSqlFS(ctx, os.DirFS(path), "*.sql")
 		}
+		return f.RunScript(ctx, os.DirFS(filepath.Dir(path)), filepath.Base(path))
 	}
 	return nil
 }
@@ -430,43 +528,26 @@ func (f *Postgres) LoadSqlPattern(ctx context.Context, pattern string) error {
 	return nil
 }
 
-// https://github.com/ory/dockertest/blob/v3/examples/PostgreSQL.md
-// https://stackoverflow.com/a/63011266
+// WaitForReady waits until postgres accepts connections over TCP from inside its container and from the tests.
 func (f *Postgres) WaitForReady(ctx context.Context, d time.Duration) error {
-	if err := Retry(d, func() error {
-		var err error
-
-		port := ContainerTcpPort(f.resource, f.docker.Network(), "5432")
-		if port == "" {
-			err = fmt.Errorf("could not get port from container: %+v", f.resource.Container)
-			return err
-		}
-		f.settings.Port = port
-
-		status, err := f.Psql(ctx, []string{"pg_isready"}, []string{}, true)
-		if err != nil {
-			return err
-		}
-		if status != 0 {
-			reason := "unknown"
-			switch status {
-			case 1:
-				reason = "server is rejecting connections"
-			case 2:
-				reason = "no response"
-			case 3:
-				reason = "no attempt was made"
+	port := f.container.Port("5432")
+	if port == "" {
+		return fmt.Errorf("could not get port from container: %+v", f.resource.Container)
+	}
+	f.settings.Port = port
+
+	// The image's entrypoint initializes the database with a server which only listens on a unix socket, so
+	// waiting for TCP inside the container skips past initialization.
+	if err := WaitFor(ctx, f.container, d,
+		ForExec("pg_isready", "--host=localhost", "--username="+f.settings.User),
+		WaitFunc(func(ctx context.Context, _ WaitTarget) error {
+			db, err := f.settings.Connect(ctx)
+			if err != nil {
+				return err
 			}
-			err = fmt.Errorf("postgres is not ready: (%v) %v", status, reason)
-			return err
-		}
-
-		db, err := f.settings.Connect(ctx)
-		if err != nil {
-			return err
-		}
-		return db.Close(ctx)
-	}); err != nil {
+			return db.Close(ctx)
+		}),
+	); err != nil {
 		return fmt.Errorf("gave up waiting for postgres: %w", err)
 	}
 
@@ -0,0 +1,44 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+// RestoreFS loads name from fsys, as written by Dump, into the primary database. Like Restore, the dump is copied
+// into the container first, so it can come from an embed.FS.
+func (f *Postgres) RestoreFS(ctx context.Context, fsys fs.FS, name string) error {
+	if err := f.container.CopyToFS(ctx, fsys, name, "/tmp"); err != nil {
+		return err
+	}
+	return f.restore(ctx, "/tmp/"+path.Base(name))
+}
+
+// LoadSqlFS runs the scripts in fsys matching pattern against the primary database. See RunScript.
+func (f *Postgres) LoadSqlFS(ctx context.Context, fsys fs.FS, pattern string) error {
+	return loadSqlFS(fsys, pattern, func(name string) error {
+		return f.RunScript(ctx, fsys, name)
+	})
+}
+
+// LoadSqlFS runs each script in fsys matching pattern, in lexical order, over a pgx connection. See RunScript.
+func LoadSqlFS(ctx context.Context, db DB, fsys fs.FS, pattern string) error {
+	return loadSqlFS(fsys, pattern, func(name string) error {
+		return RunScript(ctx, db, fsys, name)
+	})
+}
+
+func loadSqlFS(fsys fs.FS, pattern string, run func(name string) error) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err := run(name); err != nil {
+			return fmt.Errorf("failed to load sql: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package fixtures
+
+import (
+	"context"
+	"embed"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/migrations/*.sql
+var testSql embed.FS
+
+// execDB records the statements executed, failing those which are in errs.
+type execDB struct {
+	DB
+	executed []string
+	errs     map[string]error
+}
+
+func (db *execDB) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if err := db.errs[sql]; err != nil {
+		return nil, err
+	}
+	db.executed = append(db.executed, sql)
+	return pgconn.CommandTag("OK"), nil
+}
+
+func TestLoadSqlFS(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"sql/2_person.sql":  {Data: []byte("CREATE TABLE person ();")},
+		"sql/1_address.sql": {Data: []byte("CREATE TABLE address ();")},
+		"sql/empty.sql":     {Data: []byte("\n")},
+		"sql/README.md":     {Data: []byte("# sql")},
+	}
+	db := &execDB{}
+	require.NoError(t, LoadSqlFS(ctx, db, fsys, "sql/*.sql"))
+	assert.Equal(t, []string{"CREATE TABLE address ()", "CREATE TABLE person ()"}, db.executed)
+
+	db = &execDB{errs: map[string]error{"SELEC 2": &pgconn.PgError{Severity: "ERROR", Message: "syntax error", Code: "42601", Position: 1}}}
+	err := LoadSqlFS(ctx, db, fstest.MapFS{"broken.sql": {Data: []byte("SELECT 1;\nSELEC 2;")}}, "*.sql")
+	assert.EqualError(t, err, "failed to load sql: broken.sql:2: ERROR: syntax error (SQLSTATE 42601)")
+
+	db = &execDB{}
+	require.NoError(t, LoadSqlFS(ctx, db, testSql, "testdata/migrations/*.sql"))
+	assert.Len(t, db.executed, 2)
+}
+
+func TestPostgresLoadSqlFS(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+
+	require.NoError(t, pg.LoadSqlFS(ctx, testSql, "testdata/migrations/*.sql"))
+	tables, err := pg.Tables(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"address", "person"}, tables)
+}
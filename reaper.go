@@ -0,0 +1,177 @@
+package fixtures
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+const (
+	// LabelSession is set on every container and network created by go-fixtures to the ID of the test process.
+	LabelSession = "go-fixtures.session"
+	// LabelDocker is set on every container and network created through a Docker fixture to the fixture's name.
+	LabelDocker = "go-fixtures.docker"
+	// LabelCreated is the unix time a resource was created, since the docker API doesn't report it for networks.
+	LabelCreated = "go-fixtures.created"
+	// LabelReaper marks the reaper sidecar, which must not reap itself.
+	LabelReaper = "go-fixtures.reaper"
+
+	DEFAULT_REAPER_REPO    = "testcontainers/ryuk"
+	DEFAULT_REAPER_VERSION = "0.5.1"
+)
+
+var sessionID = uuid.NewString()
+
+// SessionID identifies the resources created by this process.
+func SessionID() string {
+	return sessionID
+}
+
+// sessionLabels returns the labels to set on a new resource, merged with any labels requested by the caller.
+func sessionLabels(labels map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[LabelSession] = sessionID
+	result[LabelCreated] = strconv.FormatInt(time.Now().Unix(), 10)
+	return result
+}
+
+// labels returns the session labels, scoped to this fixture so that its reaper leaves other fixtures alone.
+func (f *Docker) labels(labels map[string]string) map[string]string {
+	result := sessionLabels(labels)
+	result[LabelDocker] = f.name
+	return result
+}
+
+// Reap removes containers and networks created by go-fixtures in other sessions more than olderThan ago, such as
+// those left behind by a test process which crashed. Resources created by this process are never removed.
+func Reap(ctx context.Context, olderThan time.Duration) error {
+	pool, err := dockertest.NewPool(resolveEndpoint(""))
+	if err != nil {
+		return err
+	}
+	return reap(ctx, pool, olderThan)
+}
+
+func reap(ctx context.Context, pool *dockertest.Pool, olderThan time.Duration) error {
+	cutoff := time.Now().Add(-olderThan)
+	orphaned := func(labels map[string]string, created time.Time) bool {
+		session, ok := labels[LabelSession]
+		return ok && session != sessionID && created.Before(cutoff)
+	}
+
+	var errs Errors
+	containers, err := pool.Client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {LabelSession}},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("error listing docker containers: %w", err)
+	}
+	for _, c := range containers {
+		if !orphaned(c.Labels, createdAt(c.Labels, c.Created)) {
+			continue
+		}
+		if err := pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: c.ID, Force: true, RemoveVolumes: true, Context: ctx}); err != nil {
+			errs = append(errs, &PurgeError{Container: strings.TrimPrefix(firstOr(c.Names, c.ID), "/"), ID: c.ID, Err: err})
+		}
+	}
+
+	networks, err := pool.Client.FilteredListNetworks(docker.NetworkFilterOpts{
+		"label": {LabelSession: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error listing docker networks: %w", err)
+	}
+	for _, n := range networks {
+		if _, ok := n.Labels[LabelCreated]; !ok || !orphaned(n.Labels, createdAt(n.Labels, 0)) {
+			continue
+		}
+		if err := pool.Client.RemoveNetwork(n.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove network '%v': %w", n.Name, err))
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// createdAt returns the time recorded in LabelCreated, or fallback, a unix time, if there isn't one.
+func createdAt(labels map[string]string, fallback int64) time.Time {
+	if created, err := strconv.ParseInt(labels[LabelCreated], 10, 64); err == nil {
+		return time.Unix(created, 0)
+	}
+	return time.Unix(fallback, 0)
+}
+
+func firstOr(values []string, fallback string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return fallback
+}
+
+// startReaper runs a sidecar which removes every resource created through this fixture once the connection
+// returned is closed, including when the test process dies. The reaper is reached through its published port
+// rather than the fixture's network, since the network can't be removed while the reaper is attached to it.
+func (f *Docker) startReaper() (net.Conn, error) {
+	resource, err := f.pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   DEFAULT_REAPER_REPO,
+		Tag:          DEFAULT_REAPER_VERSION,
+		Mounts:       []string{f.socket() + ":/var/run/docker.sock"},
+		ExposedPorts: []string{"8080/tcp"},
+		Labels:       map[string]string{LabelReaper: sessionID},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		// Podman's socket can't be used from an unprivileged container.
+		hc.Privileged = f.engine == EnginePodman
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reaper: %w", err)
+	}
+
+	var conn net.Conn
+	addr := net.JoinHostPort(f.hostAddress(resource), resource.GetPort("8080/tcp"))
+	if err := Retry(30*time.Second, func() error {
+		c, err := net.DialTimeout("tcp", addr, time.Second)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(c, "label=%v=%v&label=%v=%v\n", LabelSession, sessionID, LabelDocker, f.name); err != nil {
+			c.Close()
+			return err
+		}
+		ack, err := bufio.NewReader(c).ReadString('\n')
+		if err != nil {
+			c.Close()
+			return err
+		}
+		if strings.TrimSpace(ack) != "ACK" {
+			c.Close()
+			return fmt.Errorf("unexpected response from reaper: %q", ack)
+		}
+		conn = c
+		return nil
+	}); err != nil {
+		f.pool.Purge(resource)
+		return nil, fmt.Errorf("gave up waiting for reaper: %w", err)
+	}
+	return conn, nil
+}
+
+// socket returns the host path of the daemon's socket, which the reaper needs to mount.
+func (f *Docker) socket() string {
+	if path := socketPath(f.endpoint); path != "" {
+		return path
+	}
+	return "/var/run/docker.sock"
+}
@@ -0,0 +1,89 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionLabels(t *testing.T) {
+	labels := sessionLabels(map[string]string{"foo": "bar", LabelSession: "other"})
+	assert.Equal(t, "bar", labels["foo"])
+	assert.Equal(t, SessionID(), labels[LabelSession])
+	assert.NotEmpty(t, labels[LabelCreated])
+
+	labels = (&Docker{name: "docker_a"}).labels(nil)
+	assert.Equal(t, SessionID(), labels[LabelSession])
+	assert.Equal(t, "docker_a", labels[LabelDocker])
+}
+
+func TestReap(t *testing.T) {
+	ctx := context.Background()
+
+	require.True(t, IsDockerRunning())
+
+	f := NewDocker()
+	require.NoError(t, f.SetUp(ctx))
+	defer f.TearDown(ctx)
+
+	// Pretend this container was left behind by a session which crashed long ago, so that only it is old enough
+	// to be reaped and other test runs on the host are left alone.
+	longAgo := 10 * 365 * 24 * time.Hour
+	orphan, err := f.Pool().RunWithOptions(&dockertest.RunOptions{
+		Repository: "crccheck/hello-world",
+		Tag:        "latest",
+		Labels: map[string]string{
+			LabelSession: "crashed",
+			LabelCreated: strconv.FormatInt(time.Now().Add(-longAgo).Unix(), 10),
+		},
+	})
+	require.NoError(t, err)
+	ours, err := f.RunWithOptions(&dockertest.RunOptions{Repository: "crccheck/hello-world", Tag: "latest"})
+	require.NoError(t, err)
+	defer f.Purge(ours)
+
+	require.NoError(t, Reap(ctx, longAgo-time.Hour))
+
+	_, err = f.Pool().Client.InspectContainer(orphan.Container.ID)
+	assert.IsType(t, &docker.NoSuchContainer{}, err)
+	_, err = f.Pool().Client.InspectContainer(ours.Container.ID)
+	assert.NoError(t, err)
+}
+
+func TestReaperScopedToDocker(t *testing.T) {
+	ctx := context.Background()
+
+	require.True(t, IsDockerRunning())
+
+	a := NewDocker(DockerReaper())
+	require.NoError(t, a.SetUp(ctx))
+	b := NewDocker(DockerReaper())
+	require.NoError(t, b.SetUp(ctx))
+	defer b.TearDown(ctx)
+
+	ours, err := b.RunWithOptions(&dockertest.RunOptions{Repository: "crccheck/hello-world", Tag: "latest"})
+	require.NoError(t, err)
+	defer b.Purge(ours)
+	// Left behind by a, as if it had crashed, so that only a's reaper removes it.
+	theirs, err := a.Pool().RunWithOptions(&dockertest.RunOptions{Repository: "crccheck/hello-world", Tag: "latest", Labels: a.labels(nil)})
+	require.NoError(t, err)
+	defer a.Pool().Purge(theirs)
+
+	// Tearing down a closes its reaper, which must only reap what was created through a.
+	require.NoError(t, a.TearDown(ctx))
+	require.NoError(t, Retry(time.Minute, func() error {
+		if _, err := a.Pool().Client.InspectContainer(theirs.Container.ID); err == nil {
+			return errors.New("container not reaped yet")
+		}
+		return nil
+	}))
+	_, err = b.Pool().Client.InspectContainer(ours.Container.ID)
+	assert.NoError(t, err)
+}
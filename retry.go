@@ -0,0 +1,50 @@
+package fixtures
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy controls how a fixture is retried when SetUp fails.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times SetUp is called. Values below 2 disable retries.
+	Attempts int
+	// MaxElapsedTime bounds the total time spent retrying, as with Retry. Defaults to unlimited.
+	MaxElapsedTime time.Duration
+	// Retryable reports whether an error should be retried. Defaults to retrying every error.
+	Retryable func(error) bool
+}
+
+// setUpWithRetry sets up the fixture according to its retry policy. Before each retry, the partially created
+// fixture is torn down.
+func (f *Fixtures) setUpWithRetry(ctx context.Context, e *entry) error {
+	policy := e.config.retry
+	if policy == nil || policy.Attempts < 2 {
+		return f.run(ctx, e, OpSetUp, e.fixture.SetUp)
+	}
+	var attempts Errors
+	err := retry(ctx, policy.MaxElapsedTime, policy.Attempts, func() error {
+		if len(attempts) > 0 {
+			if err := f.run(ctx, e, OpTearDown, e.fixture.TearDown); err != nil {
+				f.log.Warn("failed to teardown fixture before retrying", zap.String("fixture", e.name), zap.Error(err))
+			}
+		}
+		err := f.run(ctx, e, OpSetUp, e.fixture.SetUp)
+		if err == nil {
+			return nil
+		}
+		attempts = append(attempts, err)
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return backoff.Permanent(err)
+		}
+		f.log.Debug("retrying setup", zap.String("fixture", e.name), zap.Int("attempt", len(attempts)), zap.Error(err))
+		return err
+	})
+	if err != nil && len(attempts) > 1 {
+		return &RetryError{Attempts: attempts}
+	}
+	return err
+}
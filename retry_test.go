@@ -0,0 +1,74 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errPermanent = errors.New("permanent")
+
+type flakyFixture struct {
+	BaseFixture
+	failures  int
+	err       error
+	setUps    int
+	tearDowns int
+}
+
+func (ff *flakyFixture) SetUp(context.Context) error {
+	ff.setUps++
+	if ff.setUps <= ff.failures {
+		if ff.err != nil {
+			return ff.err
+		}
+		return fmt.Errorf("attempt %v failed", ff.setUps)
+	}
+	return nil
+}
+
+func (ff *flakyFixture) TearDown(context.Context) error {
+	ff.tearDowns++
+	return nil
+}
+
+func TestFixtureRetry(t *testing.T) {
+	ctx := context.Background()
+	fixtures := NewFixtures()
+	ff := &flakyFixture{failures: 2}
+	require.NoError(t, fixtures.AddByName(ctx, "flaky", ff, FixtureRetry(RetryPolicy{Attempts: 3})))
+	assert.Equal(t, 3, ff.setUps)
+	assert.Equal(t, 2, ff.tearDowns)
+}
+
+func TestFixtureRetryGiveUp(t *testing.T) {
+	ctx := context.Background()
+	fixtures := NewFixtures()
+	ff := &flakyFixture{failures: 5}
+	err := fixtures.AddByName(ctx, "flaky", ff, FixtureRetry(RetryPolicy{Attempts: 3}))
+	var retryErr *RetryError
+	require.ErrorAs(t, err, &retryErr)
+	require.Len(t, retryErr.Attempts, 3)
+	assert.EqualError(t, retryErr.Attempts[0], "attempt 1 failed")
+	assert.EqualError(t, retryErr.Attempts[2], "attempt 3 failed")
+	assert.Equal(t, 3, ff.setUps)
+
+	require.NoError(t, fixtures.TearDown(ctx))
+	assert.Equal(t, 3, ff.tearDowns)
+}
+
+func TestFixtureRetryNotRetryable(t *testing.T) {
+	ctx := context.Background()
+	fixtures := NewFixtures()
+	ff := &flakyFixture{failures: 5, err: errPermanent}
+	err := fixtures.AddByName(ctx, "flaky", ff, FixtureRetry(RetryPolicy{
+		Attempts:  3,
+		Retryable: func(err error) bool { return !errors.Is(err, errPermanent) },
+	}))
+	require.ErrorIs(t, err, errPermanent)
+	assert.Equal(t, 1, ff.setUps)
+}
@@ -0,0 +1,185 @@
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+const (
+	// LabelReuse is set on reusable containers to the hash of their configuration.
+	LabelReuse = "go-fixtures.reuse"
+
+	reusePrefix = "go-fixtures-reuse-"
+)
+
+// reusable reports whether the container should outlive this process to be reused by later test runs.
+func (f *Container) reusable() bool {
+	return f.reuse || f.docker.Reuse()
+}
+
+// runReusable starts the container under a name derived from its configuration, or reuses the container already
+// running under that name. Reusable containers carry no session labels, so they aren't reaped.
+func (f *Container) runReusable(opts *dockertest.RunOptions) error {
+	hash, err := f.configHash(opts)
+	if err != nil {
+		return fmt.Errorf("failed to hash container config: %w", err)
+	}
+	name := reusePrefix + hash[:12]
+	pool := f.docker.Pool()
+
+	resource, ok := pool.ContainerByName(name)
+	f.reused = ok
+	if !ok {
+		o := *opts
+		o.Name = name
+		o.Networks = nil
+		o.Labels = map[string]string{}
+		for k, v := range opts.Labels {
+			o.Labels[k] = v
+		}
+		o.Labels[LabelReuse] = hash
+		o.Labels[LabelCreated] = fmt.Sprint(time.Now().Unix())
+		if resource, err = pool.RunWithOptions(&o, f.hcOpts...); err != nil {
+			// Another test process may have started it first.
+			if resource, ok = pool.ContainerByName(name); !ok {
+				return err
+			}
+		}
+	}
+	if !resource.Container.State.Running {
+		if err := pool.Client.StartContainer(resource.Container.ID, nil); err != nil {
+			return fmt.Errorf("failed to start reused container '%v': %w", name, err)
+		}
+	}
+	if network := f.docker.Network(); network != nil {
+		if _, ok := resource.Container.NetworkSettings.Networks[network.Network.Name]; !ok {
+			if err := pool.Client.ConnectNetwork(network.Network.ID, docker.NetworkConnectionOptions{Container: resource.Container.ID}); err != nil {
+				return fmt.Errorf("failed to connect reused container '%v' to network: %w", name, err)
+			}
+			f.connected = true
+		}
+	}
+	// Inspect again to pick up the ports and addresses assigned since it was found.
+	if f.resource, ok = pool.ContainerByName(name); !ok {
+		return fmt.Errorf("reused container '%v' disappeared", name)
+	}
+	return nil
+}
+
+// release disconnects a reusable container from this session's network, leaving it running.
+func (f *Container) release() error {
+	if !f.connected {
+		return nil
+	}
+	f.connected = false
+	return f.docker.Pool().Client.DisconnectNetwork(f.docker.Network().Network.ID, docker.NetworkConnectionOptions{
+		Container: f.resource.Container.ID,
+		Force:     true,
+	})
+}
+
+// configHash identifies the container by everything which affects how it runs.
+func (f *Container) configHash(opts *dockertest.RunOptions) (string, error) {
+	hc := &docker.HostConfig{}
+	for _, opt := range f.hcOpts {
+		opt(hc)
+	}
+	tag := opts.Tag
+	if f.image != nil {
+		// Image tags are unique to the session, so use the content instead.
+		tag = f.image.Hash()
+	}
+	b, err := json.Marshal(struct {
+		Repository   string
+		Tag          string
+		Env          []string
+		Cmd          []string
+		Entrypoint   []string
+		ExposedPorts []string
+		Mounts       []string
+		Labels       map[string]string
+		HostConfig   *docker.HostConfig
+	}{opts.Repository, tag, opts.Env, opts.Cmd, opts.Entrypoint, opts.ExposedPorts, opts.Mounts, opts.Labels, hc})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ReusedContainer describes a container left running for reuse across test runs.
+type ReusedContainer struct {
+	ID      string
+	Name    string
+	Image   string
+	Hash    string
+	State   string
+	Created time.Time
+}
+
+// ListReused returns every container left running for reuse.
+func ListReused(ctx context.Context) ([]ReusedContainer, error) {
+	pool, err := dockertest.NewPool(resolveEndpoint(""))
+	if err != nil {
+		return nil, err
+	}
+	return listReused(ctx, pool)
+}
+
+func listReused(ctx context.Context, pool *dockertest.Pool) ([]ReusedContainer, error) {
+	containers, err := pool.Client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {LabelReuse}},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing docker containers: %w", err)
+	}
+	reused := make([]ReusedContainer, 0, len(containers))
+	for _, c := range containers {
+		reused = append(reused, ReusedContainer{
+			ID:      c.ID,
+			Name:    strings.TrimPrefix(firstOr(c.Names, c.ID), "/"),
+			Image:   c.Image,
+			Hash:    c.Labels[LabelReuse],
+			State:   c.State,
+			Created: time.Unix(c.Created, 0),
+		})
+	}
+	return reused, nil
+}
+
+// PruneReused removes containers left running for reuse which were created more than olderThan ago, returning
+// those removed. Pass 0 to remove them all.
+func PruneReused(ctx context.Context, olderThan time.Duration) ([]ReusedContainer, error) {
+	pool, err := dockertest.NewPool(resolveEndpoint(""))
+	if err != nil {
+		return nil, err
+	}
+	reused, err := listReused(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var pruned []ReusedContainer
+	var errs Errors
+	for _, c := range reused {
+		if c.Created.After(cutoff) {
+			continue
+		}
+		if err := pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: c.ID, Force: true, RemoveVolumes: true, Context: ctx}); err != nil {
+			errs = append(errs, &PurgeError{Container: c.Name, ID: c.ID, Err: err})
+			continue
+		}
+		pruned = append(pruned, c)
+	}
+	return pruned, errs.errorOrNil()
+}
@@ -0,0 +1,73 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerConfigHash(t *testing.T) {
+	opts := &dockertest.RunOptions{Repository: "alpine", Tag: "3", Cmd: []string{"sleep", "600"}}
+	hash := func(c *Container, opts *dockertest.RunOptions) string {
+		h, err := c.configHash(opts)
+		require.NoError(t, err)
+		return h
+	}
+
+	a := hash(NewContainer(nil), opts)
+	assert.Equal(t, a, hash(NewContainer(nil), opts))
+	assert.NotEqual(t, a, hash(NewContainer(nil, ContainerMemoryMB(256)), opts))
+	assert.NotEqual(t, a, hash(NewContainer(nil), &dockertest.RunOptions{Repository: "alpine", Tag: "3.18", Cmd: opts.Cmd}))
+}
+
+func TestContainerReuse(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	run := func(t *testing.T) *Container {
+		f := New(t)
+		d := NewDocker()
+		c := NewContainer(d,
+			ContainerRepo("alpine"),
+			ContainerTag("3"),
+			ContainerCmd("sleep", "600"),
+			ContainerLabels(map[string]string{"test": "TestContainerReuse"}),
+			ContainerReuse(),
+		)
+		f.AddT(t, d, c)
+		return c
+	}
+
+	var first *Container
+	t.Run("Create", func(t *testing.T) {
+		first = run(t)
+		assert.Empty(t, first.Resource().Container.Config.Labels[LabelSession])
+	})
+	t.Run("Reuse", func(t *testing.T) {
+		second := run(t)
+		assert.True(t, second.Reused())
+		assert.Equal(t, first.Resource().Container.ID, second.Resource().Container.ID)
+	})
+
+	reusedIDs := func() []string {
+		reused, err := ListReused(ctx)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, c := range reused {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	id := first.Resource().Container.ID
+	assert.Contains(t, reusedIDs(), id)
+
+	// Remove only this test's container, leaving other reused containers on the host alone.
+	pool, err := dockertest.NewPool(resolveEndpoint(""))
+	require.NoError(t, err)
+	require.NoError(t, pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true, RemoveVolumes: true, Context: ctx}))
+	assert.NotContains(t, reusedIDs(), id)
+}
@@ -0,0 +1,530 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Scripts may include each other, but not endlessly.
+const maxScriptDepth = 16
+
+type ScriptOpt func(*scriptRunner)
+
+// Set a variable before the script runs, like psql's --set.
+func ScriptVar(name, value string) ScriptOpt {
+	return func(r *scriptRunner) {
+		r.vars[name] = value
+	}
+}
+
+// ScriptError reports the file and line of the statement or meta-command which failed.
+type ScriptError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.File, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// RunScript runs the psql script name from fsys against the primary database. Unlike the package-level
+// RunScript, \connect may switch to another database.
+func (f *Postgres) RunScript(ctx context.Context, fsys fs.FS, name string, opts ...ScriptOpt) error {
+	connect := func(ctx context.Context, database string) (*pgxpool.Pool, error) {
+		return f.Connect(ctx, PostgresConnDatabase(database))
+	}
+	db, err := connect(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	err = runScript(ctx, db, connect, fsys, name, opts...)
+	f.log.Debug("run script", zap.String("database", f.settings.Database), zap.String("container", f.HostName()), zap.String("name", name), zap.Error(err))
+	return err
+}
+
+// RunScript runs the psql script name from fsys over a single session of db, one statement at a time. It
+// understands dollar quoting, COPY ... FROM stdin followed by inline data, variable interpolation and the
+// meta-commands \i, \ir, \set, \unset and \connect; \i resolves paths from the root of fsys. COPY requires db to be
+// a *pgxpool.Pool, *pgx.Conn or *TestTx, and \connect is only supported by Postgres.RunScript.
+func RunScript(ctx context.Context, db DB, fsys fs.FS, name string, opts ...ScriptOpt) error {
+	return runScript(ctx, db, nil, fsys, name, opts...)
+}
+
+func runScript(ctx context.Context, db DB, connect func(context.Context, string) (*pgxpool.Pool, error), fsys fs.FS, name string, opts ...ScriptOpt) error {
+	r := &scriptRunner{fsys: fsys, connect: connect, vars: map[string]string{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	defer r.close()
+	if err := r.use(ctx, db); err != nil {
+		return err
+	}
+	return r.run(ctx, name, 0)
+}
+
+// scriptDB is the part of DB which scripts need, which a pgx.Tx also provides.
+type scriptDB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+}
+
+type scriptRunner struct {
+	fsys    fs.FS
+	connect func(context.Context, string) (*pgxpool.Pool, error)
+	vars    map[string]string
+	// db runs statements, and conn runs COPY over the same session if it's known.
+	db      scriptDB
+	conn    *pgx.Conn
+	closers []func()
+}
+
+// use runs the rest of the script in one session of db.
+func (r *scriptRunner) use(ctx context.Context, db DB) error {
+	switch d := db.(type) {
+	case *pgxpool.Pool:
+		c, err := d.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		r.closers = append(r.closers, c.Release)
+		r.db, r.conn = c.Conn(), c.Conn()
+	case *pgx.Conn:
+		r.db, r.conn = d, d
+	case *TestTx:
+		r.db, r.conn = d, d.tx.Conn()
+	default:
+		r.db, r.conn = db, nil
+	}
+	return nil
+}
+
+func (r *scriptRunner) close() {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		r.closers[i]()
+	}
+}
+
+// scriptStatement accumulates a statement as it's scanned. Comments before the statement are dropped.
+type scriptStatement struct {
+	sql     strings.Builder
+	content bool
+	start   int
+	line    int
+}
+
+// begin marks the start of the statement, unless it has already started.
+func (s *scriptStatement) begin(line int) {
+	if !s.content {
+		s.content = true
+		s.start = s.sql.Len()
+		s.line = line
+	}
+}
+
+// text returns the statement without surrounding whitespace.
+func (s *scriptStatement) text() string {
+	return strings.TrimSpace(s.sql.String()[s.start:])
+}
+
+func (r *scriptRunner) run(ctx context.Context, name string, depth int) error {
+	if depth > maxScriptDepth {
+		return &ScriptError{File: name, Err: fmt.Errorf("scripts are included more than %v deep", maxScriptDepth)}
+	}
+	b, err := fs.ReadFile(r.fsys, name)
+	if err != nil {
+		return &ScriptError{File: name, Err: err}
+	}
+	src := string(b)
+	line := 1
+	stmt := &scriptStatement{}
+	emit := func(s string) {
+		stmt.sql.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			j := indexFrom(src, i, "\n")
+			emit(src[i:j])
+			i = j
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			j := blockCommentEnd(src, i)
+			emit(src[i:j])
+			i = j
+		case c == '\'':
+			j := quoteEnd(src, i, '\'', escapeString(src, i))
+			stmt.begin(line)
+			emit(src[i:j])
+			i = j
+		case c == '"':
+			j := quoteEnd(src, i, '"', false)
+			stmt.begin(line)
+			emit(src[i:j])
+			i = j
+		case c == '$' && (i == 0 || !isIdentByte(src[i-1])) && dollarTag(src[i:]) != "":
+			tag := dollarTag(src[i:])
+			j := indexFrom(src, i+len(tag), tag)
+			if j < len(src) {
+				j += len(tag)
+			}
+			stmt.begin(line)
+			emit(src[i:j])
+			i = j
+		case c == ':':
+			s, n := r.interpolate(src[i:])
+			stmt.begin(line)
+			stmt.sql.WriteString(s)
+			i += n
+		case c == '\\':
+			j := indexFrom(src, i, "\n")
+			if err := r.meta(ctx, name, depth, src[i+1:j]); err != nil {
+				var scriptErr *ScriptError
+				if errors.As(err, &scriptErr) {
+					return err
+				}
+				return &ScriptError{File: name, Line: line, Err: err}
+			}
+			i = j
+		case c == ';':
+			i++
+			if stmt.content {
+				sql, first := stmt.text(), stmt.line
+				if copyFromStdin.MatchString(sql) {
+					data, next := copyData(src, i)
+					dataLine := line + 1
+					line += strings.Count(src[i:next], "\n")
+					i = next
+					if err := r.copyFrom(ctx, sql, data); err != nil {
+						return &ScriptError{File: name, Line: copyErrorLine(err, first, dataLine), Err: err}
+					}
+				} else if err := r.exec(ctx, sql); err != nil {
+					return &ScriptError{File: name, Line: first + errorLine(sql, err) - 1, Err: err}
+				}
+			}
+			stmt = &scriptStatement{}
+		default:
+			if c == '\n' {
+				line++
+			} else if c != ' ' && c != '\t' && c != '\r' {
+				stmt.begin(line)
+			}
+			stmt.sql.WriteByte(c)
+			i++
+		}
+	}
+	// Like psql, run a final statement which isn't terminated by a semicolon.
+	if stmt.content {
+		sql, first := stmt.text(), stmt.line
+		if err := r.exec(ctx, sql); err != nil {
+			return &ScriptError{File: name, Line: first + errorLine(sql, err) - 1, Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *scriptRunner) exec(ctx context.Context, sql string) error {
+	// Without arguments, pgx runs the statement as a simple query.
+	_, err := r.db.Exec(ctx, sql)
+	return err
+}
+
+var copyFromStdin = regexp.MustCompile(`(?is)^COPY\b.*\bFROM\s+STDIN\b`)
+
+func (r *scriptRunner) copyFrom(ctx context.Context, sql string, data string) error {
+	if r.conn == nil {
+		return errors.New("COPY FROM stdin needs a *pgxpool.Pool, *pgx.Conn or *TestTx")
+	}
+	_, err := r.conn.PgConn().CopyFrom(ctx, strings.NewReader(data), sql)
+	return err
+}
+
+// copyData returns the inline data of a COPY statement which ends at i, and the index of the end of the line
+// which terminates it.
+func copyData(src string, i int) (string, int) {
+	// The data starts on the line after the statement.
+	start := indexFrom(src, i, "\n")
+	if start < len(src) {
+		start++
+	}
+	for end := start; end < len(src); {
+		next := indexFrom(src, end, "\n")
+		if strings.TrimRight(src[end:next], "\r") == `\.` {
+			return src[start:end], next
+		}
+		end = next + 1
+	}
+	return src[start:], len(src)
+}
+
+var copyContext = regexp.MustCompile(`COPY \S+, line (\d+)`)
+
+// copyErrorLine returns the line of the inline data at which postgres reported err, or the statement's line.
+func copyErrorLine(err error, stmtLine int, dataLine int) int {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if m := copyContext.FindStringSubmatch(pgErr.Where); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+				return dataLine + n - 1
+			}
+		}
+	}
+	return stmtLine
+}
+
+// meta runs a meta-command, which is everything after the backslash up to the end of the line.
+func (r *scriptRunner) meta(ctx context.Context, name string, depth int, line string) error {
+	cmd := line
+	rest := ""
+	if i := strings.IndexAny(line, " \t\r"); i >= 0 {
+		cmd, rest = line[:i], line[i:]
+	}
+	args, err := r.metaArgs(rest)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "i", "include", "ir", "include_relative":
+		if len(args) != 1 {
+			return fmt.Errorf(`\%v expects a file`, cmd)
+		}
+		file := args[0]
+		if cmd == "ir" || cmd == "include_relative" {
+			file = path.Join(path.Dir(name), file)
+		}
+		return r.run(ctx, path.Clean(file), depth+1)
+	case "set":
+		if len(args) == 0 {
+			return errors.New(`\set expects a name`)
+		}
+		r.vars[args[0]] = strings.Join(args[1:], "")
+		return nil
+	case "unset":
+		if len(args) != 1 {
+			return errors.New(`\unset expects a name`)
+		}
+		delete(r.vars, args[0])
+		return nil
+	case "c", "connect":
+		if r.connect == nil {
+			return fmt.Errorf(`\%v is only supported by Postgres.RunScript`, cmd)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf(`\%v expects a database`, cmd)
+		}
+		for _, arg := range args[1:] {
+			if arg != "-" {
+				return fmt.Errorf(`\%v can only change the database`, cmd)
+			}
+		}
+		db, err := r.connect(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		r.closers = append(r.closers, db.Close)
+		return r.use(ctx, db)
+	}
+	return fmt.Errorf(`unsupported meta-command \%v`, cmd)
+}
+
+// metaArgs splits the arguments of a meta-command on whitespace. Like psql, single quotes group an argument and
+// variables are interpolated.
+func (r *scriptRunner) metaArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+			i++
+			continue
+		case c == '\'':
+			j := quoteEnd(s, i, '\'', false)
+			if j > len(s) || s[j-1] != '\'' || j-i < 2 {
+				return nil, errors.New("unterminated quoted string")
+			}
+			arg.WriteString(strings.ReplaceAll(s[i+1:j-1], "''", "'"))
+			i = j
+		case c == ':':
+			v, n := r.interpolate(s[i:])
+			arg.WriteString(v)
+			i += n
+		default:
+			arg.WriteByte(c)
+			i++
+		}
+		inArg = true
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+var scriptVariable = regexp.MustCompile(`^:(?:([A-Za-z0-9_]+)|'([A-Za-z0-9_]+)'|"([A-Za-z0-9_]+)")`)
+
+// interpolate substitutes the variable at the start of s, which starts with a colon, returning the text to use
+// and the number of bytes consumed. Like psql, :name is replaced by its value, :'name' by its value as a literal
+// and :"name" by its value as an identifier. Casts and unset variables are left alone.
+func (r *scriptRunner) interpolate(s string) (string, int) {
+	if strings.HasPrefix(s, "::") {
+		return "::", 2
+	}
+	m := scriptVariable.FindStringSubmatch(s)
+	if m == nil {
+		return ":", 1
+	}
+	switch {
+	case m[1] != "":
+		if v, ok := r.vars[m[1]]; ok {
+			return v, len(m[0])
+		}
+	case m[2] != "":
+		if v, ok := r.vars[m[2]]; ok {
+			return "'" + strings.ReplaceAll(v, "'", "''") + "'", len(m[0])
+		}
+	case m[3] != "":
+		if v, ok := r.vars[m[3]]; ok {
+			return pgx.Identifier{v}.Sanitize(), len(m[0])
+		}
+	}
+	return ":", 1
+}
+
+// indexFrom returns the index of substr in s at or after i, or len(s).
+func indexFrom(s string, i int, substr string) int {
+	if j := strings.Index(s[i:], substr); j >= 0 {
+		return i + j
+	}
+	return len(s)
+}
+
+// blockCommentEnd returns the index after the comment starting at i. Block comments nest.
+func blockCommentEnd(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// quoteEnd returns the index after the quoted string starting at i. A doubled quote is part of the string, as
+// is a backslash-escaped character in an escape string.
+func quoteEnd(s string, i int, quote byte, escapes bool) int {
+	for i++; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// escapeString reports whether the quote at i starts an escape string, such as E'\n'.
+func escapeString(s string, i int) bool {
+	return i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i < 2 || !isIdentByte(s[i-2]))
+}
+
+// splitStatements splits sql into its statements, without comments or the semicolons which end them. Unlike a
+// script, sql is sent as is: variables and meta-commands aren't interpreted.
+func splitStatements(sql string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	content := false
+	flush := func() {
+		if content {
+			stmts = append(stmts, strings.TrimSpace(stmt.String()))
+		}
+		stmt.Reset()
+		content = false
+	}
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		j := i + 1
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			i = indexFrom(sql, i, "\n")
+			stmt.WriteByte(' ')
+			continue
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = blockCommentEnd(sql, i)
+			stmt.WriteByte(' ')
+			continue
+		case c == ';':
+			flush()
+			i++
+			continue
+		case c == '\'':
+			j = quoteEnd(sql, i, '\'', escapeString(sql, i))
+		case c == '"':
+			j = quoteEnd(sql, i, '"', false)
+		case c == '$' && (i == 0 || !isIdentByte(sql[i-1])) && dollarTag(sql[i:]) != "":
+			tag := dollarTag(sql[i:])
+			if j = indexFrom(sql, i+len(tag), tag); j < len(sql) {
+				j += len(tag)
+			}
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			content = true
+		}
+		stmt.WriteString(sql[i:j])
+		i = j
+	}
+	flush()
+	return stmts
+}
+
+var dollarQuote = regexp.MustCompile(`^\$(?:[A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*)?\$`)
+
+// dollarTag returns the dollar quote, e.g. $body$, at the start of s, or "".
+func dollarTag(s string) string {
+	return dollarQuote.FindString(s)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,139 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScript(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"main.sql": {Data: []byte(`-- Create the schema.
+\set schema app
+\set owner 'o''brien'
+CREATE SCHEMA :"schema";
+SELECT 'a;b', E'c\';d', "e;f" FROM t; /* g; /* h; */ i; */
+CREATE FUNCTION f() RETURNS int AS $body$
+BEGIN
+  RETURN 1; -- $$ ;
+END
+$body$ LANGUAGE plpgsql;
+SELECT :'owner', :unset, x::int, $1;
+\i sub/data.sql
+SELECT 'tail'`)},
+		"sub/data.sql": {Data: []byte("\\ir more.sql\n")},
+		"sub/more.sql": {Data: []byte("INSERT INTO t VALUES (:'schema');\n-- done\n")},
+	}
+	db := &execDB{}
+	require.NoError(t, RunScript(ctx, db, fsys, "main.sql"))
+	assert.Equal(t, []string{
+		`CREATE SCHEMA "app"`,
+		`SELECT 'a;b', E'c\';d', "e;f" FROM t`,
+		"CREATE FUNCTION f() RETURNS int AS $body$\nBEGIN\n  RETURN 1; -- $$ ;\nEND\n$body$ LANGUAGE plpgsql",
+		`SELECT 'o''brien', :unset, x::int, $1`,
+		`INSERT INTO t VALUES ('app')`,
+		`SELECT 'tail'`,
+	}, db.executed)
+
+	db = &execDB{}
+	require.NoError(t, RunScript(ctx, db, fstest.MapFS{"vars.sql": {Data: []byte("SELECT :n;")}}, "vars.sql", ScriptVar("n", "42")))
+	assert.Equal(t, []string{"SELECT 42"}, db.executed)
+}
+
+func TestRunScriptErrors(t *testing.T) {
+	ctx := context.Background()
+	syntaxErr := &pgconn.PgError{Severity: "ERROR", Message: "syntax error", Code: "42601", Position: 17}
+	fsys := fstest.MapFS{
+		"broken.sql":  {Data: []byte("SELECT 1;\n\n-- comment\nSELECT\n  1,\n  2 2;\n")},
+		"include.sql": {Data: []byte("SELECT 1;\n\\i broken.sql\n")},
+		"copy.sql":    {Data: []byte("COPY t FROM stdin;\n1\n\\.\n")},
+		"connect.sql": {Data: []byte("\\connect other\n")},
+		"meta.sql":    {Data: []byte("SELECT 1;\n\\gexec\n")},
+		"missing.sql": {Data: []byte("\\i nope.sql\n")},
+		"loop.sql":    {Data: []byte("\\i loop.sql\n")},
+	}
+
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{"broken.sql", "broken.sql:6: ERROR: syntax error (SQLSTATE 42601)"},
+		{"include.sql", "broken.sql:6: ERROR: syntax error (SQLSTATE 42601)"},
+		{"copy.sql", "copy.sql:1: COPY FROM stdin needs a *pgxpool.Pool, *pgx.Conn or *TestTx"},
+		{"connect.sql", `connect.sql:1: \connect is only supported by Postgres.RunScript`},
+		{"meta.sql", `meta.sql:2: unsupported meta-command \gexec`},
+		{"missing.sql", "nope.sql: open nope.sql: file does not exist"},
+		{"loop.sql", "loop.sql: scripts are included more than 16 deep"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &execDB{errs: map[string]error{"SELECT\n  1,\n  2 2": syntaxErr}}
+			err := RunScript(ctx, db, fsys, tt.name)
+			assert.EqualError(t, err, tt.err)
+			var scriptErr *ScriptError
+			assert.ErrorAs(t, err, &scriptErr)
+		})
+	}
+}
+
+func TestCopyData(t *testing.T) {
+	src := "COPY t FROM stdin;\n1\tone\n2\ttwo\n\\.\nSELECT 1;"
+	data, next := copyData(src, len("COPY t FROM stdin;"))
+	assert.Equal(t, "1\tone\n2\ttwo\n", data)
+	assert.Equal(t, "\nSELECT 1;", src[next:])
+
+	data, next = copyData("COPY t FROM stdin;\n1\n", len("COPY t FROM stdin;"))
+	assert.Equal(t, "1\n", data)
+	assert.Equal(t, len("COPY t FROM stdin;\n1\n"), next)
+
+	assert.Equal(t, 7, copyErrorLine(&pgconn.PgError{Where: "COPY t, line 2, column id: \"x\""}, 4, 6))
+	assert.Equal(t, 4, copyErrorLine(&pgconn.PgError{}, 4, 6))
+}
+
+func TestPostgresRunScript(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+	require.NoError(t, pg.CreateDatabase(ctx, "other"))
+
+	fsys := fstest.MapFS{
+		"load.sql": {Data: []byte(`CREATE TABLE item (id int PRIMARY KEY, name text);
+CREATE FUNCTION item_count() RETURNS bigint AS $$
+  SELECT count(*) FROM item;
+$$ LANGUAGE sql;
+
+COPY item (id, name) FROM stdin;
+1	one
+2	two
+\.
+
+\set table item
+\connect other
+CREATE TABLE :"table" (id int);
+`)},
+		"badcopy.sql": {Data: []byte("SELECT 1;\nCOPY item (id, name) FROM stdin;\n3\tthree\nx\tfour\n\\.\n")},
+	}
+	require.NoError(t, pg.RunScript(ctx, fsys, "load.sql"))
+
+	db := pg.MustConnect(ctx)
+	defer db.Close()
+	var count int64
+	require.NoError(t, db.QueryRow(ctx, "SELECT item_count()").Scan(&count))
+	assert.Equal(t, int64(2), count)
+	other := pg.MustConnect(ctx, PostgresConnDatabase("other"))
+	defer other.Close()
+	_, err := other.Exec(ctx, "SELECT FROM item")
+	assert.NoError(t, err)
+
+	err = pg.RunScript(ctx, fsys, "badcopy.sql")
+	assert.ErrorContains(t, err, "badcopy.sql:4:")
+}
@@ -0,0 +1,265 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+const DEFAULT_TEMPLATE_WARM = 2
+
+var errTemplateClosed = errors.New("template is closed")
+
+type TemplateOpt func(*Template)
+
+// Name the template database. Defaults to "<database>_template".
+func TemplateName(name string) TemplateOpt {
+	return func(t *Template) {
+		t.name = name
+	}
+}
+
+// Keep n clones created in the background, ready for IsolatedDB. Defaults to 2.
+func TemplateWarm(n int) TemplateOpt {
+	return func(t *Template) {
+		t.warm = n
+	}
+}
+
+// Template is a database which is migrated once and cloned for each test. Create one with Postgres.Template.
+type Template struct {
+	log    *zap.Logger
+	admin  *pgxpool.Pool
+	name   string
+	warm   int
+	clones chan string
+	// mu serializes clones, since postgres refuses to copy a template which is being copied concurrently.
+	mu     sync.Mutex
+	closed bool
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Template creates a template database, runs setup against it, e.g. to apply migrations, and starts cloning it in
+// the background for IsolatedDB. If the database already exists, as it may in a reused container, it is replaced.
+func (f *Postgres) Template(ctx context.Context, setup func(context.Context, *pgxpool.Pool) error, opts ...TemplateOpt) (*Template, error) {
+	f.templateMu.Lock()
+	defer f.templateMu.Unlock()
+	return f.newTemplate(ctx, setup, opts...)
+}
+
+func (f *Postgres) newTemplate(ctx context.Context, setup func(context.Context, *pgxpool.Pool) error, opts ...TemplateOpt) (*Template, error) {
+	if f.template != nil {
+		return nil, errors.New("postgres already has a template")
+	}
+	t := &Template{
+		log:  f.log,
+		name: f.settings.Database + "_template",
+		warm: DEFAULT_TEMPLATE_WARM,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	var err error
+	if t.admin, err = f.Connect(ctx); err != nil {
+		return nil, err
+	}
+	if err := t.create(ctx, f, setup); err != nil {
+		t.admin.Close()
+		return nil, fmt.Errorf("failed to create template database '%v': %w", t.name, err)
+	}
+
+	t.clones = make(chan string, t.warm)
+	warmCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	if t.warm > 0 {
+		t.wg.Add(1)
+		go t.warmUp(warmCtx)
+	}
+	f.template = t
+	return t, nil
+}
+
+func (t *Template) create(ctx context.Context, f *Postgres, setup func(context.Context, *pgxpool.Pool) error) error {
+	ident := pgx.Identifier{t.name}.Sanitize()
+	var exists bool
+	if err := t.admin.QueryRow(ctx, "SELECT EXISTS (SELECT FROM pg_database WHERE datname = $1)", t.name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		// Template databases can't be dropped.
+		if _, err := t.admin.Exec(ctx, "ALTER DATABASE "+ident+" IS_TEMPLATE false"); err != nil {
+			return err
+		}
+		if err := dropDatabase(ctx, t.admin, t.name); err != nil {
+			return err
+		}
+	}
+	if _, err := t.admin.Exec(ctx, "CREATE DATABASE "+ident+" TEMPLATE template0"); err != nil {
+		return err
+	}
+	if setup != nil {
+		db, err := f.Connect(ctx, PostgresConnDatabase(t.name))
+		if err != nil {
+			return err
+		}
+		err = setup(ctx, db)
+		db.Close()
+		if err != nil {
+			return err
+		}
+	}
+	// Cloning fails while anything is connected to the template, so stop anything from connecting.
+	if err := terminateConnections(ctx, t.admin, t.name); err != nil {
+		return err
+	}
+	_, err := t.admin.Exec(ctx, "ALTER DATABASE "+ident+" WITH IS_TEMPLATE true ALLOW_CONNECTIONS false")
+	return err
+}
+
+// clone creates a new database from the template.
+func (t *Template) clone(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return "", errTemplateClosed
+	}
+	name, err := createDatabase(ctx, t.admin, t.name, t.name)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone template database '%v': %w", t.name, err)
+	}
+	return name, nil
+}
+
+// take returns a pre-warmed clone if one is ready, otherwise it creates one.
+func (t *Template) take(ctx context.Context) (string, error) {
+	select {
+	case name, ok := <-t.clones:
+		if !ok {
+			return "", errTemplateClosed
+		}
+		return name, nil
+	default:
+		return t.clone(ctx)
+	}
+}
+
+// warmUp keeps the clones channel full until ctx is done.
+func (t *Template) warmUp(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		name, err := t.clone(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				t.log.Warn("stopped warming template clones", zap.String("template", t.name), zap.Error(err))
+			}
+			return
+		}
+		select {
+		case t.clones <- name:
+		case <-ctx.Done():
+			if err := dropDatabase(context.Background(), t.admin, name); err != nil {
+				t.log.Warn("failed to drop database", zap.String("database", name), zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// close stops warming clones and drops those which were never used.
+func (t *Template) close(ctx context.Context) error {
+	t.cancel()
+	t.wg.Wait()
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+	close(t.clones)
+	var errs Errors
+	for name := range t.clones {
+		if err := dropDatabase(ctx, t.admin, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.admin.Close()
+	return errs.errorOrNil()
+}
+
+// IsolatedDB gives the test a fresh clone of the template database, which is dropped when the test completes.
+// Without a template, the test gets an empty database. If the database can't be created, the test is stopped
+// with t.Fatalf.
+func (f *Postgres) IsolatedDB(t testing.TB, opts ...PostgresConnOpt) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+	f.templateMu.Lock()
+	tmpl := f.template
+	f.templateMu.Unlock()
+
+	var admin *pgxpool.Pool
+	var name string
+	var err error
+	if tmpl != nil {
+		admin = tmpl.admin
+		name, err = tmpl.take(ctx)
+	} else {
+		// Without a template there's nothing to warm, so the empty database is created here and its admin
+		// connection is closed along with it.
+		if admin, err = f.Connect(ctx); err != nil {
+			t.Fatalf("%v", err)
+		}
+		t.Cleanup(admin.Close)
+		if name, err = createDatabase(ctx, admin, f.settings.Database+"_isolated", "template0"); err != nil {
+			err = fmt.Errorf("failed to create database: %w", err)
+		}
+	}
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	db, err := f.Connect(ctx, append(opts, PostgresConnDatabase(name))...)
+	if err != nil {
+		dropDatabase(ctx, admin, name)
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if err := dropDatabase(ctx, admin, name); err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+	return db
+}
+
+// terminateConnections disconnects every session connected to the database.
+func terminateConnections(ctx context.Context, admin *pgxpool.Pool, name string) error {
+	_, err := admin.Exec(ctx, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", name)
+	return err
+}
+
+// createDatabase creates a copy of template named prefix with a random suffix, and returns its name.
+func createDatabase(ctx context.Context, admin *pgxpool.Pool, prefix, template string) (string, error) {
+	name := prefix + "_" + uuid.NewString()[:8]
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()+" TEMPLATE "+pgx.Identifier{template}.Sanitize()); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func dropDatabase(ctx context.Context, admin *pgxpool.Pool, name string) error {
+	if err := terminateConnections(ctx, admin, name); err != nil {
+		return err
+	}
+	if _, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to drop database '%v': %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package fixtures
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresIsolatedDB(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+
+	tmpl, err := pg.Template(ctx, func(ctx context.Context, db *pgxpool.Pool) error {
+		_, err := db.Exec(ctx, "CREATE TABLE widget (id serial PRIMARY KEY, name text NOT NULL)")
+		return err
+	})
+	require.NoError(t, err)
+
+	databases := func() []string {
+		db, err := pg.Connect(ctx)
+		require.NoError(t, err)
+		defer db.Close()
+		rows, err := db.Query(ctx, "SELECT datname FROM pg_database WHERE datname LIKE $1", tmpl.Name()+"_%")
+		require.NoError(t, err)
+		defer rows.Close()
+		names := []string{}
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	var mu sync.Mutex
+	used := []string{}
+	t.Run("Group", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			t.Run("Test", func(t *testing.T) {
+				t.Parallel()
+				db := pg.IsolatedDB(t)
+				// Each test sees only its own rows.
+				_, err := db.Exec(ctx, "INSERT INTO widget (name) VALUES ('a')")
+				require.NoError(t, err)
+				var count int
+				require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM widget").Scan(&count))
+				assert.Equal(t, 1, count)
+				mu.Lock()
+				used = append(used, db.Config().ConnConfig.Database)
+				mu.Unlock()
+			})
+		}
+	})
+	require.Len(t, used, 3)
+	remaining := databases()
+	for _, name := range used {
+		assert.NotContains(t, remaining, name)
+	}
+}
+
+func TestPostgresIsolatedDBWithoutTemplate(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+
+	t.Run("Empty", func(t *testing.T) {
+		db := pg.IsolatedDB(t)
+		tables, err := pg.Tables(ctx, db.Config().ConnConfig.Database)
+		require.NoError(t, err)
+		assert.Empty(t, tables)
+	})
+
+	// An empty database doesn't stand in for a template, so one can still be created afterwards.
+	_, err := pg.Template(ctx, nil)
+	require.NoError(t, err)
+}
+
+func TestTemplateClosed(t *testing.T) {
+	tmpl := &Template{clones: make(chan string), closed: true}
+	close(tmpl.clones)
+	_, err := tmpl.take(context.Background())
+	assert.ErrorIs(t, err, errTemplateClosed)
+	_, err = tmpl.clone(context.Background())
+	assert.ErrorIs(t, err, errTemplateClosed)
+}
@@ -0,0 +1,8 @@
+CREATE TABLE address (
+    id SERIAL PRIMARY KEY,
+    street TEXT,
+    city TEXT,
+    state TEXT,
+    country TEXT,
+    zip TEXT
+);
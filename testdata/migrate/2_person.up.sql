@@ -0,0 +1,6 @@
+CREATE TABLE person (
+    id SERIAL PRIMARY KEY,
+    first_name TEXT,
+    last_name TEXT,
+    address_id INT REFERENCES address
+);
@@ -0,0 +1,189 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+)
+
+// New returns Fixtures bound to a test. Logs are written through t.Log, and every fixture is torn down when
+// the test and all of its subtests have completed. If the test failed, the output captured by each fixture
+// is logged before teardown.
+func New(t testing.TB, opts ...FixturesOpt) *Fixtures {
+	t.Helper()
+	f := NewFixtures(append([]FixturesOpt{FixturesLogger(zaptest.NewLogger(t))}, opts...)...)
+	t.Cleanup(func() {
+		if t.Failed() {
+			f.mu.Lock()
+			entries := f.tearDownOrder(nil)
+			f.mu.Unlock()
+			reportLogs(t.Logf, entries)
+		}
+		if err := f.TearDown(context.Background()); err != nil {
+			t.Errorf("failed to tear down fixtures: %v", err)
+		}
+	})
+	return f
+}
+
+// AddT sets up fixtures for the scope of a test. The fixtures, and anything added later which depends on them,
+// are torn down and removed when t completes, so fixtures added in a subtest don't outlive it.
+// If setup fails, the test is stopped with t.Fatalf. If the test failed, the output captured by the fixtures
+// is logged before teardown.
+func (f *Fixtures) AddT(t testing.TB, fixtures ...Fixture) {
+	t.Helper()
+	names := make([]string, 0, len(fixtures))
+	t.Cleanup(func() {
+		if t.Failed() {
+			f.mu.Lock()
+			entries := f.tearDownOrder(func(e *entry) bool {
+				for _, name := range names {
+					if e.name == name {
+						return true
+					}
+				}
+				return false
+			})
+			f.mu.Unlock()
+			reportLogs(t.Logf, entries)
+		}
+		if err := f.release(context.Background(), names...); err != nil {
+			t.Errorf("failed to tear down fixtures: %v", err)
+		}
+	})
+	for _, fix := range fixtures {
+		name := GetRandomName(0)
+		names = append(names, name)
+		if err := f.AddByName(context.Background(), name, fix); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+}
+
+// release tears down the named fixtures and their dependents, then removes them.
+func (f *Fixtures) release(ctx context.Context, names ...string) error {
+	f.mu.Lock()
+	released := f.dependents(names...)
+	entries := f.tearDownOrder(func(e *entry) bool { return released[e.name] })
+	f.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+	errs := f.tearDown(ctx, entries)
+	f.mu.Lock()
+	for name := range released {
+		f.remove(name)
+	}
+	f.mu.Unlock()
+	return errs.errorOrNil()
+}
+
+// LogWriter returns a writer which logs each write through t.Log, e.g. for ContainerLogOutput. Writes made
+// after the test has completed are discarded.
+func LogWriter(t testing.TB) io.Writer {
+	w := &testLogWriter{t: t}
+	t.Cleanup(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.done = true
+	})
+	return w
+}
+
+type testLogWriter struct {
+	mu   sync.Mutex
+	t    testing.TB
+	done bool
+}
+
+func (w *testLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// RunMain sets up a package-wide suite of fixtures, runs the tests and tears the fixtures down, returning the
+// exit code to pass to os.Exit. It is meant to be called from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(fixtures.RunMain(m, func(ctx context.Context, f *fixtures.Fixtures) error {
+//			return f.Add(ctx, fixtures.NewDocker())
+//		}))
+//	}
+//
+// Any fixtures build registers without setting up are set up before the tests run. Fixtures are torn down
+// when the tests finish, when build panics, and when the process receives SIGINT or SIGTERM. A panic inside
+// a test terminates the process from the test's goroutine, so it can't be recovered here.
+func RunMain(m *testing.M, build func(context.Context, *Fixtures) error, opts ...FixturesOpt) int {
+	return runMain(m.Run, build, opts...)
+}
+
+func runMain(run func() int, build func(context.Context, *Fixtures) error, opts ...FixturesOpt) (code int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := NewFixtures(opts...)
+
+	// tearDown reports whether the fixtures were torn down cleanly. It may run on the signal goroutine, so only the
+	// main goroutine sets code.
+	var once sync.Once
+	var tornDown bool
+	tearDown := func() bool {
+		once.Do(func() {
+			err := f.TearDown(context.Background())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "go-fixtures: failed to tear down fixtures:\n%v\n", err)
+			}
+			tornDown = err == nil
+		})
+		return tornDown
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "go-fixtures: received %v, tearing down fixtures\n", sig)
+			cancel()
+			tearDown()
+			os.Exit(1)
+		case <-ctx.Done():
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tearDown()
+			panic(r)
+		}
+	}()
+
+	err := build(ctx, f)
+	if err == nil {
+		err = f.SetUp(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-fixtures: failed to set up fixtures:\n%v\n", err)
+		code = 1
+		tearDown()
+		return code
+	}
+
+	code = run()
+	if !tearDown() && code == 0 {
+		code = 1
+	}
+	return code
+}
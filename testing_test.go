@@ -0,0 +1,102 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+	var f *Fixtures
+
+	t.Run("Suite", func(t *testing.T) {
+		f = New(t)
+		f.AddT(t, docker)
+
+		t.Run("Subtest", func(t *testing.T) {
+			pg := &recordingFixture{name: "postgres", deps: []Fixture{docker}, events: &events}
+			f.AddT(t, pg)
+			assert.Equal(t, []string{"setup docker", "setup postgres"}, events)
+		})
+		assert.Equal(t, []string{"setup docker", "setup postgres", "teardown postgres"}, events)
+
+		t.Run("DependentOutlivesSubtest", func(t *testing.T) {
+			pg := &recordingFixture{name: "postgres2", deps: []Fixture{docker}, events: &events}
+			// Added through the suite, so it is released along with docker.
+			assert.NoError(t, f.Add(context.Background(), pg))
+		})
+	})
+
+	assert.Equal(t, []string{
+		"setup docker", "setup postgres", "teardown postgres",
+		"setup postgres2", "teardown postgres2", "teardown docker",
+	}, events)
+	assert.Empty(t, f.order)
+}
+
+func TestRunMain(t *testing.T) {
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+	pg := &recordingFixture{name: "postgres", deps: []Fixture{docker}, events: &events}
+
+	code := runMain(func() int {
+		events = append(events, "run")
+		return 0
+	}, func(ctx context.Context, f *Fixtures) error {
+		return f.Register(pg, docker)
+	})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []string{"setup docker", "setup postgres", "run", "teardown postgres", "teardown docker"}, events)
+}
+
+func TestRunMainSetUpFailure(t *testing.T) {
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+
+	code := runMain(func() int {
+		events = append(events, "run")
+		return 0
+	}, func(ctx context.Context, f *Fixtures) error {
+		if err := f.Add(ctx, docker); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	assert.Equal(t, 1, code)
+	assert.Equal(t, []string{"setup docker", "teardown docker"}, events)
+}
+
+func TestRunMainPanic(t *testing.T) {
+	events := []string{}
+	docker := &recordingFixture{name: "docker", events: &events}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		runMain(func() int {
+			return 0
+		}, func(ctx context.Context, f *Fixtures) error {
+			if err := f.Add(ctx, docker); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"setup docker", "teardown docker"}, events)
+}
+
+func TestLogWriter(t *testing.T) {
+	var w io.Writer
+	t.Run("Test", func(t *testing.T) {
+		w = LogWriter(t)
+		n, err := w.Write([]byte("[container] hello\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 18, n)
+	})
+	// Writing after the test completes must not panic.
+	_, err := w.Write([]byte("late\n"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,202 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// DB is implemented by both *pgxpool.Pool and *TestTx, so code under test which accepts a DB can run in a test
+// transaction.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+	BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error
+}
+
+// TestTx runs every statement inside one transaction, which is rolled back when the test completes. Transactions
+// begun by the code under test, whether through Begin or by executing BEGIN and COMMIT, become savepoints.
+// Like a single connection, it must not be used concurrently.
+type TestTx struct {
+	tx         pgx.Tx
+	savepoints []string
+	next       int
+}
+
+// BeginTestTx opens a transaction for the scope of a test. Only the database and role connection options apply;
+// the role is assumed with SET LOCAL ROLE. If the transaction can't be opened, the test is stopped with t.Fatalf.
+func (f *Postgres) BeginTestTx(t testing.TB, opts ...PostgresConnOpt) *TestTx {
+	t.Helper()
+	ctx := context.Background()
+	cfg := &PostgresConnConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	pool, err := f.Connect(ctx, PostgresConnDatabase(cfg.database))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		pool.Close()
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(context.Background()); err != nil {
+			t.Errorf("failed to roll back test transaction: %v", err)
+		}
+		pool.Close()
+	})
+	if cfg.role != "" {
+		if _, err := tx.Exec(ctx, "SET LOCAL ROLE "+pgx.Identifier{cfg.role}.Sanitize()); err != nil {
+			t.Fatalf("failed to assume role '%v': %v", cfg.role, err)
+		}
+	}
+	return &TestTx{tx: tx}
+}
+
+// Tx returns the test transaction.
+func (t *TestTx) Tx() pgx.Tx {
+	return t.tx
+}
+
+// Exec runs sql in the test transaction. BEGIN, COMMIT and ROLLBACK are run as savepoints instead, including
+// when they're among several statements, so statements without arguments are run one at a time.
+func (t *TestTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	// Postgres only accepts several statements without arguments.
+	if len(arguments) == 0 {
+		if stmts := splitStatements(sql); len(stmts) > 0 {
+			var tag pgconn.CommandTag
+			for _, stmt := range stmts {
+				var err error
+				if tag, err = t.exec(ctx, stmt); err != nil {
+					return nil, err
+				}
+			}
+			return tag, nil
+		}
+	}
+	return t.exec(ctx, sql, arguments...)
+}
+
+func (t *TestTx) exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	switch txCommand(sql) {
+	case "begin":
+		t.next++
+		sp := fmt.Sprintf("test_tx_%v", t.next)
+		if _, err := t.tx.Exec(ctx, "SAVEPOINT "+sp); err != nil {
+			return nil, err
+		}
+		t.savepoints = append(t.savepoints, sp)
+		return pgconn.CommandTag("BEGIN"), nil
+	case "commit":
+		sp, ok := t.pop()
+		if ok {
+			if _, err := t.tx.Exec(ctx, "RELEASE SAVEPOINT "+sp); err != nil {
+				return nil, err
+			}
+		}
+		return pgconn.CommandTag("COMMIT"), nil
+	case "rollback":
+		sp, ok := t.pop()
+		if ok {
+			if _, err := t.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+sp); err != nil {
+				return nil, err
+			}
+			if _, err := t.tx.Exec(ctx, "RELEASE SAVEPOINT "+sp); err != nil {
+				return nil, err
+			}
+		}
+		return pgconn.CommandTag("ROLLBACK"), nil
+	}
+	return t.tx.Exec(ctx, sql, arguments...)
+}
+
+// pop removes the innermost savepoint begun with BEGIN. Like postgres, COMMIT and ROLLBACK outside of a
+// transaction do nothing.
+func (t *TestTx) pop() (string, bool) {
+	if len(t.savepoints) == 0 {
+		return "", false
+	}
+	sp := t.savepoints[len(t.savepoints)-1]
+	t.savepoints = t.savepoints[:len(t.savepoints)-1]
+	return sp, true
+}
+
+func (t *TestTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return t.tx.Query(ctx, sql, args...)
+}
+
+func (t *TestTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return t.tx.QueryRow(ctx, sql, args...)
+}
+
+func (t *TestTx) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	return t.tx.QueryFunc(ctx, sql, args, scans, f)
+}
+
+func (t *TestTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return t.tx.SendBatch(ctx, b)
+}
+
+func (t *TestTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return t.tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Begin starts a savepoint.
+func (t *TestTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.tx.Begin(ctx)
+}
+
+// BeginTx starts a savepoint. Transaction options can't apply to a savepoint, so they are ignored.
+func (t *TestTx) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return t.tx.Begin(ctx)
+}
+
+func (t *TestTx) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	return t.tx.BeginFunc(ctx, f)
+}
+
+// BeginTxFunc runs f in a savepoint. Transaction options are ignored, as for BeginTx.
+func (t *TestTx) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	return t.tx.BeginFunc(ctx, f)
+}
+
+// txCommand classifies a transaction control statement as "begin", "commit" or "rollback". Other statements,
+// including ROLLBACK TO SAVEPOINT, return "".
+func txCommand(sql string) string {
+	fields := strings.Fields(strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(sql), ";")))
+	if len(fields) == 0 {
+		return ""
+	}
+	bare := len(fields) == 1 || len(fields) == 2 && (fields[1] == "WORK" || fields[1] == "TRANSACTION")
+	switch fields[0] {
+	case "BEGIN":
+		// BEGIN may be followed by transaction modes, which can't apply to a savepoint.
+		return "begin"
+	case "START":
+		if len(fields) > 1 && fields[1] == "TRANSACTION" {
+			return "begin"
+		}
+	case "COMMIT", "END":
+		if bare {
+			return "commit"
+		}
+	case "ROLLBACK", "ABORT":
+		if bare {
+			return "rollback"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ DB = (*pgxpool.Pool)(nil)
+	_ DB = (*TestTx)(nil)
+)
+
+func TestTxCommand(t *testing.T) {
+	for sql, cmd := range map[string]string{
+		"BEGIN":                               "begin",
+		"begin;":                              "begin",
+		"BEGIN ISOLATION LEVEL SERIALIZABLE":  "begin",
+		"start transaction":                   "begin",
+		"COMMIT":                              "commit",
+		" commit work; ":                      "commit",
+		"END":                                 "commit",
+		"ROLLBACK":                            "rollback",
+		"abort transaction":                   "rollback",
+		"ROLLBACK TO SAVEPOINT sp":            "",
+		"COMMIT PREPARED 'x'":                 "",
+		"SELECT 1":                            "",
+		"":                                    "",
+		"INSERT INTO t (begin) VALUES (true)": "",
+	} {
+		assert.Equal(t, cmd, txCommand(sql), sql)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO t VALUES ('a;b', E'c\\';d', \"e;f\")",
+		"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END $$ LANGUAGE plpgsql",
+		"COMMIT",
+	}, splitStatements("BEGIN; -- start\nINSERT INTO t VALUES ('a;b', E'c\\';d', \"e;f\");\n"+
+		"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END $$ LANGUAGE plpgsql;\n/* end; */ COMMIT -- done"))
+	assert.Empty(t, splitStatements(" -- nothing\n;"))
+}
+
+func TestPostgresBeginTestTx(t *testing.T) {
+	require.True(t, IsDockerRunning())
+
+	ctx := context.Background()
+	f := New(t)
+	d := NewDocker()
+	pg := NewPostgres(d)
+	f.AddT(t, d, pg)
+
+	db, err := pg.Connect(ctx)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(ctx, "CREATE TABLE widget (id serial PRIMARY KEY, name text NOT NULL)")
+	require.NoError(t, err)
+
+	count := func(t *testing.T, db DB) int {
+		var n int
+		require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM widget").Scan(&n))
+		return n
+	}
+
+	t.Run("Rollback", func(t *testing.T) {
+		tx := pg.BeginTestTx(t)
+		_, err := tx.Exec(ctx, "INSERT INTO widget (name) VALUES ('a')")
+		require.NoError(t, err)
+
+		// Raw transaction control becomes savepoints.
+		_, err = tx.Exec(ctx, "BEGIN")
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "INSERT INTO widget (name) VALUES ('b')")
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "ROLLBACK")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count(t, tx))
+
+		// So do transactions begun through pgx.
+		require.NoError(t, tx.BeginFunc(ctx, func(nested pgx.Tx) error {
+			_, err := nested.Exec(ctx, "INSERT INTO widget (name) VALUES ('c')")
+			return err
+		}))
+		assert.Equal(t, 2, count(t, tx))
+	})
+
+	t.Run("MultipleStatements", func(t *testing.T) {
+		tx := pg.BeginTestTx(t)
+		_, err := tx.Exec(ctx, "BEGIN; INSERT INTO widget (name) VALUES ('d'); COMMIT;")
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "INSERT INTO widget (name) VALUES ('e'); COMMIT -- done")
+		require.NoError(t, err)
+		assert.Equal(t, 2, count(t, tx))
+	})
+
+	assert.Equal(t, 0, count(t, db))
+}
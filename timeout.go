@@ -0,0 +1,91 @@
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// timeout returns the deadline configured for the fixture's op, falling back to the Fixtures default.
+func (f *Fixtures) timeout(e *entry, op string) time.Duration {
+	if op == OpSetUp {
+		if e.config.setUpTimeout > 0 {
+			return e.config.setUpTimeout
+		}
+		return f.setUpTimeout
+	}
+	if e.config.tearDownTimeout > 0 {
+		return e.config.tearDownTimeout
+	}
+	return f.tearDownTimeout
+}
+
+// withTimeout calls fn with a context bounded by the fixture's timeout. A fixture which ignores its context is
+// abandoned once the deadline passes, so one hung fixture can't hang the whole suite. The abandoned call isn't
+// waited for, but the fixture's next SetUp or TearDown waits for it to return, for no longer than its own timeout,
+// or the abandoned call's timeout if it has none. If it still hasn't returned, the next call is skipped with a
+// TimeoutError.
+func (f *Fixtures) withTimeout(ctx context.Context, e *entry, op string, fn func(context.Context) error) error {
+	d := f.timeout(e, op)
+	if err := e.waitAbandoned(ctx, op, d); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fn(ctx)
+	}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	// Only the fixture's own deadline is a timeout; the parent's deadline or cancellation is returned as is.
+	timedOut := func(err error) error {
+		if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return &TimeoutError{Name: e.name, Type: fixtureType(e.fixture), Op: op, Timeout: d, Err: err}
+		}
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return timedOut(err)
+		}
+		return nil
+	case <-ctx.Done():
+		e.abandoned = &abandonedCall{op: op, timeout: d, done: done}
+		return timedOut(ctx.Err())
+	}
+}
+
+// abandonedCall is a SetUp or TearDown which timed out but hasn't returned yet.
+type abandonedCall struct {
+	op      string
+	timeout time.Duration
+	done    <-chan error
+}
+
+// waitAbandoned waits up to d, or the abandoned call's timeout if d is 0, for a call which previously timed out
+// to return. The entry's lock must be held.
+func (e *entry) waitAbandoned(ctx context.Context, op string, d time.Duration) error {
+	if e.abandoned == nil {
+		return nil
+	}
+	if d <= 0 {
+		d = e.abandoned.timeout
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-e.abandoned.done:
+		e.abandoned = nil
+		return nil
+	case <-timer.C:
+		err := fmt.Errorf("%v, which timed out earlier, is still running", e.abandoned.op)
+		return &TimeoutError{Name: e.name, Type: fixtureType(e.fixture), Op: op, Timeout: d, Err: err}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hungFixture struct {
+	BaseFixture
+	hangSetUp    bool
+	hangTearDown bool
+}
+
+func (hf *hungFixture) SetUp(context.Context) error {
+	if hf.hangSetUp {
+		select {}
+	}
+	return nil
+}
+
+func (hf *hungFixture) TearDown(context.Context) error {
+	if hf.hangTearDown {
+		select {}
+	}
+	return nil
+}
+
+func TestFixturesSetUpTimeout(t *testing.T) {
+	ctx := context.Background()
+	fixtures := NewFixtures(FixturesSetUpTimeout(time.Minute))
+	err := fixtures.AddByName(ctx, "hung", &hungFixture{hangSetUp: true}, FixtureSetUpTimeout(10*time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "hung", timeoutErr.Name)
+	assert.Equal(t, OpSetUp, timeoutErr.Op)
+	assert.Equal(t, 10*time.Millisecond, timeoutErr.Timeout)
+}
+
+func TestFixturesTearDownTimeout(t *testing.T) {
+	ctx := context.Background()
+	events := []string{}
+	fixtures := NewFixtures(FixturesTearDownTimeout(10 * time.Millisecond))
+	require.NoError(t, fixtures.AddByName(ctx, "first", &recordingFixture{name: "first", events: &events}))
+	require.NoError(t, fixtures.AddByName(ctx, "hung", &hungFixture{hangTearDown: true}))
+
+	err := fixtures.TearDown(ctx)
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "hung", timeoutErr.Name)
+	assert.Equal(t, OpTearDown, timeoutErr.Op)
+	assert.Equal(t, []string{"setup first", "teardown first"}, events)
+}
+
+func TestFixturesSetUpParentDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	fixtures := NewFixtures(FixturesSetUpTimeout(time.Minute))
+	err := fixtures.AddByName(ctx, "hung", &hungFixture{hangSetUp: true})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var timeoutErr *TimeoutError
+	assert.False(t, errors.As(err, &timeoutErr))
+}
+
+// stuckFixture ignores its context while setting up, until release is closed.
+type stuckFixture struct {
+	BaseFixture
+	release  chan struct{}
+	setUp    bool
+	tornDown bool
+}
+
+func (sf *stuckFixture) SetUp(context.Context) error {
+	<-sf.release
+	sf.setUp = true
+	return nil
+}
+
+func (sf *stuckFixture) TearDown(context.Context) error {
+	sf.tornDown = sf.setUp
+	return nil
+}
+
+func TestFixturesTearDownWaitsForAbandonedSetUp(t *testing.T) {
+	ctx := context.Background()
+	slow := &stuckFixture{release: make(chan struct{})}
+	fixtures := NewFixtures(FixturesTearDownTimeout(time.Minute))
+	err := fixtures.AddByName(ctx, "slow", slow, FixtureSetUpTimeout(10*time.Millisecond))
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+
+	time.AfterFunc(10*time.Millisecond, func() { close(slow.release) })
+	require.NoError(t, fixtures.TearDown(ctx))
+	assert.True(t, slow.tornDown)
+}
+
+func TestFixturesTearDownSkipsHungSetUp(t *testing.T) {
+	ctx := context.Background()
+	events := []string{}
+	fixtures := NewFixtures(FixturesSetUpTimeout(20 * time.Millisecond))
+	require.NoError(t, fixtures.AddByName(ctx, "first", &recordingFixture{name: "first", events: &events}))
+	err := fixtures.AddByName(ctx, "hung", &hungFixture{hangSetUp: true})
+	var timeoutErr *TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fixtures.TearDown(ctx)
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("teardown waited for the hung setup")
+	}
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.Equal(t, "hung", timeoutErr.Name)
+	assert.Equal(t, OpTearDown, timeoutErr.Op)
+	assert.Equal(t, 20*time.Millisecond, timeoutErr.Timeout)
+	assert.Equal(t, []string{"setup first", "teardown first"}, events)
+}
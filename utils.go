@@ -1,6 +1,7 @@
 package fixtures
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
@@ -44,8 +45,18 @@ func FindPath(containing string) string {
 }
 
 func Retry(d time.Duration, op func() error) error {
+	return retry(context.Background(), d, 0, op)
+}
+
+// retry calls op with exponential backoff until it succeeds, d has elapsed, attempts calls have been made or ctx
+// is done. Zero d or attempts are unlimited. Wrap an error with backoff.Permanent to stop immediately.
+func retry(ctx context.Context, d time.Duration, attempts int, op func() error) error {
 	bo := backoff.NewExponentialBackOff()
 	bo.MaxInterval = time.Second
 	bo.MaxElapsedTime = time.Duration(d)
-	return backoff.Retry(op, bo)
+	var b backoff.BackOff = bo
+	if attempts > 0 {
+		b = backoff.WithMaxRetries(b, uint64(attempts-1))
+	}
+	return backoff.Retry(op, backoff.WithContext(b, ctx))
 }
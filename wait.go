@@ -0,0 +1,317 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// WaitTarget is a running container which a WaitStrategy can inspect.
+type WaitTarget interface {
+	Docker() *Docker
+	Resource() *dockertest.Resource
+	// Address returns the "host:port" at which the tests can reach the given container port.
+	Address(port string) string
+}
+
+// WaitStrategy decides when a container is ready to use.
+type WaitStrategy interface {
+	// WaitUntilReady blocks until the target is ready or ctx is done.
+	WaitUntilReady(ctx context.Context, target WaitTarget) error
+}
+
+// WaitFor waits for every strategy to pass, giving up after timeout.
+func WaitFor(ctx context.Context, target WaitTarget, timeout time.Duration, strategies ...WaitStrategy) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return WaitAll(strategies...).WaitUntilReady(ctx, target)
+}
+
+// WaitError is returned when a WaitStrategy gives up. Last is the reason the final check failed.
+type WaitError struct {
+	Strategy string
+	Err      error
+	Last     error
+}
+
+func (e *WaitError) Error() string {
+	if e.Last == nil {
+		return fmt.Sprintf("gave up waiting for %v: %v", e.Strategy, e.Err)
+	}
+	return fmt.Sprintf("gave up waiting for %v: %v: last error: %v", e.Strategy, e.Err, e.Last)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// errStopWaiting wraps a failure which won't resolve itself by waiting longer.
+type errStopWaiting struct {
+	err error
+}
+
+func (e *errStopWaiting) Error() string {
+	return e.err.Error()
+}
+
+const waitInterval = 100 * time.Millisecond
+
+// poll runs fn until it passes, fails permanently, or ctx is done.
+func poll(ctx context.Context, strategy string, fn func(context.Context) error) error {
+	var last error
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var stop *errStopWaiting
+		if errors.As(err, &stop) {
+			return &WaitError{Strategy: strategy, Err: stop.err}
+		}
+		last = err
+		select {
+		case <-ctx.Done():
+			return &WaitError{Strategy: strategy, Err: ctx.Err(), Last: last}
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// WaitFunc is a custom check which is polled until it returns nil.
+type WaitFunc func(context.Context, WaitTarget) error
+
+func (w WaitFunc) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	return check{name: "ready check", fn: w}.WaitUntilReady(ctx, target)
+}
+
+// check is a named WaitFunc.
+type check struct {
+	name string
+	fn   func(context.Context, WaitTarget) error
+}
+
+func (c check) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	return poll(ctx, c.name, func(ctx context.Context) error {
+		return c.fn(ctx, target)
+	})
+}
+
+// ForListeningPort waits until a TCP connection can be made to the container port.
+func ForListeningPort(port string) WaitStrategy {
+	return check{name: "port " + port, fn: func(ctx context.Context, target WaitTarget) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", target.Address(port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}}
+}
+
+// HTTPWait waits for an HTTP endpoint to respond. Create one with ForHTTP.
+type HTTPWait struct {
+	port     string
+	path     string
+	statuses []int
+	body     *regexp.Regexp
+}
+
+// ForHTTP waits until a GET request to path on the container port succeeds. By default, any 2xx status passes.
+func ForHTTP(port string, path string) *HTTPWait {
+	return &HTTPWait{port: port, path: path}
+}
+
+// WithStatus only accepts the given status codes.
+func (w *HTTPWait) WithStatus(statuses ...int) *HTTPWait {
+	w.statuses = statuses
+	return w
+}
+
+// WithBody only accepts responses whose body matches the regular expression.
+func (w *HTTPWait) WithBody(pattern string) *HTTPWait {
+	w.body = regexp.MustCompile(pattern)
+	return w
+}
+
+func (w *HTTPWait) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	return poll(ctx, "http "+w.path, func(ctx context.Context) error {
+		url := fmt.Sprintf("http://%v/%v", target.Address(w.port), strings.TrimPrefix(w.path, "/"))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return &errStopWaiting{err}
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if !w.acceptStatus(resp.StatusCode) {
+			return fmt.Errorf("unexpected status: %v", resp.Status)
+		}
+		if w.body != nil {
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			if !w.body.Match(body) {
+				return fmt.Errorf("body does not match %v", w.body)
+			}
+		}
+		return nil
+	})
+}
+
+func (w *HTTPWait) acceptStatus(status int) bool {
+	if len(w.statuses) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, s := range w.statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// LogWait waits for a line to appear in the container logs. Create one with ForLog.
+type LogWait struct {
+	pattern     *regexp.Regexp
+	occurrences int
+}
+
+// ForLog waits until the regular expression matches the container's stdout or stderr.
+func ForLog(pattern string) *LogWait {
+	return &LogWait{pattern: regexp.MustCompile(pattern), occurrences: 1}
+}
+
+// WithOccurrences waits for the pattern to match n times, e.g. for servers which restart after initializing.
+func (w *LogWait) WithOccurrences(n int) *LogWait {
+	w.occurrences = n
+	return w
+}
+
+func (w *LogWait) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	return poll(ctx, "log "+w.pattern.String(), func(ctx context.Context) error {
+		var buf bytes.Buffer
+		if err := target.Docker().Pool().Client.Logs(docker.LogsOptions{
+			Context:      ctx,
+			Container:    target.Resource().Container.ID,
+			OutputStream: &buf,
+			ErrorStream:  &buf,
+			Stdout:       true,
+			Stderr:       true,
+		}); err != nil {
+			return err
+		}
+		if n := len(w.pattern.FindAllIndex(buf.Bytes(), -1)); n < w.occurrences {
+			return fmt.Errorf("found %v of %v occurrences", n, w.occurrences)
+		}
+		return nil
+	})
+}
+
+// ForHealthCheck waits until docker reports the container's HEALTHCHECK as healthy.
+func ForHealthCheck() WaitStrategy {
+	return check{name: "health check", fn: func(ctx context.Context, target WaitTarget) error {
+		c, err := target.Docker().Pool().Client.InspectContainerWithContext(target.Resource().Container.ID, ctx)
+		if err != nil {
+			return err
+		}
+		if c.State.Health.Status == "" {
+			return &errStopWaiting{errors.New("container has no health check")}
+		}
+		if c.State.Health.Status != "healthy" {
+			return fmt.Errorf("container is %v", c.State.Health.Status)
+		}
+		return nil
+	}}
+}
+
+// ExecWait waits for a command run inside the container to succeed. Create one with ForExec.
+type ExecWait struct {
+	cmd      []string
+	exitCode int
+}
+
+// ForExec waits until the command exits with status 0 inside the container.
+func ForExec(cmd ...string) *ExecWait {
+	return &ExecWait{cmd: cmd}
+}
+
+// WithExitCode waits for the command to exit with the given status instead.
+func (w *ExecWait) WithExitCode(exitCode int) *ExecWait {
+	w.exitCode = exitCode
+	return w
+}
+
+func (w *ExecWait) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	return poll(ctx, "exec "+strings.Join(w.cmd, " "), func(ctx context.Context) error {
+		res, err := target.Docker().Exec(ctx, target.Resource(), w.cmd, ExecOptions{})
+		if err != nil {
+			return err
+		}
+		if res.ExitCode != w.exitCode {
+			return fmt.Errorf("exited with status %v: %v", res.ExitCode, strings.TrimSpace(res.Stdout+res.Stderr))
+		}
+		return nil
+	})
+}
+
+type allWait []WaitStrategy
+
+// WaitAll waits for each strategy in turn.
+func WaitAll(strategies ...WaitStrategy) WaitStrategy {
+	return allWait(strategies)
+}
+
+func (w allWait) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	for _, s := range w {
+		if err := s.WaitUntilReady(ctx, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type anyWait []WaitStrategy
+
+// WaitAny waits concurrently for the first strategy to pass. If none do, every failure is returned.
+func WaitAny(strategies ...WaitStrategy) WaitStrategy {
+	return anyWait(strategies)
+}
+
+func (w anyWait) WaitUntilReady(ctx context.Context, target WaitTarget) error {
+	if len(w) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan error, len(w))
+	for _, s := range w {
+		go func(s WaitStrategy) {
+			results <- s.WaitUntilReady(ctx, target)
+		}(s)
+	}
+	var errs Errors
+	for range w {
+		err := <-results
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,110 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTarget routes every container port to addr.
+type fakeTarget struct {
+	addr string
+}
+
+func (t *fakeTarget) Docker() *Docker                { return nil }
+func (t *fakeTarget) Resource() *dockertest.Resource { return nil }
+func (t *fakeTarget) Address(port string) string     { return t.addr }
+
+func TestWaitFor(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	err := WaitFor(ctx, &fakeTarget{}, time.Second, WaitFunc(func(ctx context.Context, target WaitTarget) error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	ctx := context.Background()
+	err := WaitFor(ctx, &fakeTarget{}, 50*time.Millisecond, WaitFunc(func(ctx context.Context, target WaitTarget) error {
+		return errors.New("still starting")
+	}))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var waitErr *WaitError
+	require.ErrorAs(t, err, &waitErr)
+	assert.EqualError(t, waitErr.Last, "still starting")
+	assert.Contains(t, err.Error(), "last error: still starting")
+}
+
+func TestWaitAny(t *testing.T) {
+	ctx := context.Background()
+	never := WaitFunc(func(ctx context.Context, target WaitTarget) error {
+		return errors.New("never")
+	})
+	always := WaitFunc(func(ctx context.Context, target WaitTarget) error {
+		return nil
+	})
+	require.NoError(t, WaitFor(ctx, &fakeTarget{}, time.Second, WaitAny(never, always)))
+
+	err := WaitFor(ctx, &fakeTarget{}, 50*time.Millisecond, WaitAny(never, never))
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+
+	err = WaitFor(ctx, &fakeTarget{}, 50*time.Millisecond, WaitAll(always, never))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestForListeningPort(t *testing.T) {
+	ctx := context.Background()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, WaitFor(ctx, &fakeTarget{addr: addr}, time.Second, ForListeningPort("5432")))
+
+	l.Close()
+	err = WaitFor(ctx, &fakeTarget{addr: addr}, 50*time.Millisecond, ForListeningPort("5432"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "port 5432")
+}
+
+func TestForHTTP(t *testing.T) {
+	ctx := context.Background()
+	ready := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !ready {
+			ready = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "status: ok")
+	}))
+	defer srv.Close()
+	target := &fakeTarget{addr: strings.TrimPrefix(srv.URL, "http://")}
+
+	require.NoError(t, WaitFor(ctx, target, time.Second, ForHTTP("80", "/health").WithBody("status: ok")))
+
+	err := WaitFor(ctx, target, 50*time.Millisecond, ForHTTP("80", "/health").WithStatus(http.StatusNoContent))
+	assert.Contains(t, err.Error(), "unexpected status: 200 OK")
+	err = WaitFor(ctx, target, 50*time.Millisecond, ForHTTP("80", "/health").WithBody("^down"))
+	assert.Contains(t, err.Error(), "body does not match")
+}